@@ -19,6 +19,7 @@ var envCmd = &cobra.Command{
 		repositoryPath := args[0]
 		project, err := internal.NewProject(repositoryPath, branch, false, "")
 		internal.FailOnError(err)
+		project.ConfigFormat = configFormat
 		depsBasePath := filepath.Dir(repositoryPath)
 		err = project.AddInPlaceDepsSources(depsBasePath)
 		internal.FailOnError(err)
@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/k8s-school/ciux/cmd/util"
 	"github.com/k8s-school/ciux/internal"
@@ -26,6 +27,7 @@ var configPathCmd = &cobra.Command{
 		project, err := internal.NewProject(repositoryPath, branch, main, labelSelector)
 
 		internal.FailOnError(err)
+		project.ConfigFormat = configFormat
 		configPath, err := project.GetCiuxConfigFilepath()
 		internal.FailOnError(err)
 		// Check if the config file exists
@@ -39,6 +41,8 @@ var configPathCmd = &cobra.Command{
 func init() {
 	getCmd.AddCommand(configPathCmd)
 
+	configPathCmd.Flags().StringVarP(&configFormat, "format", "o", internal.DefaultConfigFormat, "Format of the CIUXCONFIG file, one of: "+strings.Join(internal.ConfigFormats(), ", "))
+
 	util.AddLabelSelectorFlagVar(configPathCmd, &labelSelector)
 }
 
@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/k8s-school/ciux/internal"
 	"github.com/spf13/cobra"
@@ -14,7 +15,10 @@ import (
 var check bool
 var env bool
 var suffix string
-var tmpRegistry string
+var (
+	tmpRegistry     string
+	onRegistryError string
+)
 
 // imageCmd represents the revision command
 var imageCmd = &cobra.Command{
@@ -23,9 +27,12 @@ var imageCmd = &cobra.Command{
 	Short:   "Retrieve the version of a container image, based on the source code used to build it",
 	Long: `Retrieve the version of a container image, based on the source code used to build it.
 If source code has not been modified in the current commit, ciux will return an previously built image with the current code if this image is available in the registry.
+If source code is modified in the worktree, the image is always built, with a "-dirty" tag suffix.
 - Use "sourcePathes" in the .ciux configuration file to specify the pathes to source code used to build the container image
-this pathes are relatives and must be used in the image's Dockerfile COPY/ADD commands
-- Use "registry" in the .ciux configuration file to specify the registry where the image is stored`,
+this pathes are relatives and must be used in the image's Dockerfile COPY/ADD commands, use 'ciux lint' to check them,
+or 'autoSourcePathes: Dockerfile' to compute them from the Dockerfile COPY/ADD commands
+- Use "registry" in the .ciux configuration file to specify the registry where the image is stored
+- Use --on-registry-error=fail to exit with error if the registry is unreachable or denies access, instead of building the image`,
 	Example: `# Check if image registry/<project_name>-<image-suffix>:<tag> exists
 # tag is in the format vX.Y.Z[-rcT]-N-g<short-commit-hash>
 ciux get image --check <path_to_git_repository> --suffix <image_suffix>`,
@@ -33,7 +40,9 @@ ciux get image --check <path_to_git_repository> --suffix <image_suffix>`,
 	Run: func(cmd *cobra.Command, args []string) {
 		repositoryPath := internal.AbsPath(args[0])
 		project, _, err := internal.NewCoreProject(repositoryPath, branch)
+		internal.FailOnError(err)
 		project.TemporaryRegistry = tmpRegistry
+		project.OnRegistryError, err = internal.ParseRegistryErrorPolicy(onRegistryError)
 		internal.FailOnError(err)
 		err = project.GetImageName(suffix, check)
 		internal.FailOnError(err)
@@ -54,6 +63,7 @@ func init() {
 	imageCmd.Flags().BoolVarP(&check, "check", "c", false, "Check if an image with same source code is already available in the registry, if not exit with error and print the name of the image to build")
 	imageCmd.Flags().StringVarP(&suffix, "suffix", "p", "", "Suffix to add to the image name")
 	imageCmd.Flags().StringVarP(&tmpRegistry, "tmp-registry", "t", "", "Name of temporary registry used to store the image during the ci process")
+	imageCmd.Flags().StringVar(&onRegistryError, "on-registry-error", string(internal.DefaultRegistryErrorPolicy), "Behaviour if the registry can not be checked for an existing image, one of: "+strings.Join(internal.RegistryErrorPolicies(), ", "))
 	imageCmd.Flags().BoolVarP(&env, "env", "e", false, "Print environment variables to use the image in the CI process, CIUX_IMAGE_URL and CIUX_BUILD")
 }
 
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -11,6 +12,8 @@ import (
 
 var branch string
 var main bool
+var configFormat string
+var requireDigestMatch bool
 
 // igniteCmd represents the revision command
 var igniteCmd = &cobra.Command{
@@ -19,11 +22,19 @@ var igniteCmd = &cobra.Command{
 	Short:   "Prepare integration test",
 	Long: `Retrieve current revision of the repository and clone all dependencies in the correct revision.
 	Check if dependencies container images are available.
+	With 'checkBaseImages: true' in repository_path/.ciux, the base images of the Dockerfiles inside sourcePathes
+	are also image dependencies, they are checked first if the label selector matches build=true.
 	Use repository_path/.ciux configuration file to retrieve dependencies.
-	Also compute the name for the container image to build:
+	Also compute the name for the container image to build, and for each image of the 'images' section of repository_path/.ciux:
 	It uses the sourcePathes in repository_path/.ciux to retrieve the latest git commit where some code has changed,
 	then it checks if and image exists in-between this commit and the current one and it returns it,
 	if not it set image name for the current commit.
+	If the registry can not be checked, because of a network or authentication error,
+	--on-registry-error tells if ciux fails or if it builds the image.
+	Finally write the CIUXCONFIG file and a lock file which records the exact revisions, image digests
+	and package versions of all selected dependencies.
+	With --require-digest-match, ciux fails if the digest of an image differs from the one recorded
+	in the previous lock file, i.e. if an image tag has been overwritten.
 	`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -36,6 +47,20 @@ var igniteCmd = &cobra.Command{
 
 		internal.FailOnError(err)
 		project.TemporaryRegistry = tmpRegistry
+		project.ConfigFormat = configFormat
+		project.OnRegistryError, err = internal.ParseRegistryErrorPolicy(onRegistryError)
+		internal.FailOnError(err)
+
+		// Check the base images of the Dockerfiles before retrieving the dependencies, to fail early
+		baseImages, err := project.CheckBaseImages()
+		internal.FailOnError(err)
+		if len(baseImages) != 0 {
+			var baseMsg string
+			for _, image := range baseImages {
+				baseMsg += "  " + image.Name() + "\n"
+			}
+			internal.Infof("Available base images:\n%s", strings.TrimRight(baseMsg, "\n"))
+		}
 		depsBasePath := filepath.Dir(repositoryPath)
 
 		// Retrieve dependencies sources
@@ -57,6 +82,25 @@ var igniteCmd = &cobra.Command{
 		internal.FailOnError(err)
 		internal.Infof("Image:\n%s", project.Image)
 
+		// Additional images, with their own source pathes
+		err = project.GetImagesNames(true)
+		internal.FailOnError(err)
+		for _, image := range project.Images {
+			internal.Infof("Image %s:\n%s", image.Name, image)
+		}
+		for _, image := range project.IgnoredImages {
+			if len(image.MissingPlatforms) != 0 {
+				internal.Warnf("Image %s is ignored, missing platforms: %s", image.Url(), strings.Join(image.MissingPlatforms, ", "))
+			} else {
+				internal.Warnf("Image %s is ignored, base images have changed: %s", image.Url(), strings.Join(image.OutdatedBaseImages, ", "))
+			}
+		}
+
+		if requireDigestMatch {
+			err = checkPreviousDigests(project)
+			internal.FailOnError(err)
+		}
+
 		goMsg = strings.TrimRight(goMsg, "\n")
 		internal.Infof("Go modules installed:\n%s", goMsg)
 
@@ -72,9 +116,31 @@ var igniteCmd = &cobra.Command{
 		msg, err := project.WriteOutConfig()
 		internal.FailOnError(err)
 		internal.Infof("%s", msg)
+
+		// Write lock file, for traceability and replay
+		msg, err = project.WriteLockFile()
+		internal.FailOnError(err)
+		internal.Infof("%s", msg)
 	},
 }
 
+// checkPreviousDigests compares the image digests with the ones of the previous lock file, if any
+func checkPreviousDigests(project internal.Project) error {
+	lockFilepath, err := project.GetLockFilepath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(lockFilepath); os.IsNotExist(err) {
+		internal.Warnf("No previous lock file %s, image digests are not checked", lockFilepath)
+		return nil
+	}
+	previous, err := internal.ReadLockFile(lockFilepath)
+	if err != nil {
+		return err
+	}
+	return project.CheckDigests(previous)
+}
+
 func init() {
 	rootCmd.AddCommand(igniteCmd)
 
@@ -82,7 +148,10 @@ func init() {
 	igniteCmd.Flags().BoolVarP(&main, "main", "m", false, "Only work with main project, ignore dependencies, --selector is ignored")
 	igniteCmd.PersistentFlags().StringVarP(&branch, "branch", "b", "", "current branch for the project, retrieved from git if not specified")
 	igniteCmd.Flags().StringVarP(&suffix, "suffix", "p", "", "Suffix to add to the image name")
+	igniteCmd.PersistentFlags().StringVarP(&configFormat, "format", "o", internal.DefaultConfigFormat, "Format of the CIUXCONFIG file, one of: "+strings.Join(internal.ConfigFormats(), ", "))
 	igniteCmd.Flags().StringVarP(&tmpRegistry, "tmp-registry", "t", "", "Name of temporary registry used to store the image during the ci process")
+	igniteCmd.Flags().BoolVar(&requireDigestMatch, "require-digest-match", false, "Fail if an image digest differs from the one recorded in the previous lock file")
+	igniteCmd.Flags().StringVar(&onRegistryError, "on-registry-error", string(internal.DefaultRegistryErrorPolicy), "Behaviour if the registry can not be checked for an existing image, one of: "+strings.Join(internal.RegistryErrorPolicies(), ", "))
 
 	util.AddLabelSelectorFlagVar(igniteCmd, &labelSelector)
 }
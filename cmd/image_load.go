@@ -29,29 +29,46 @@ import (
 )
 
 var (
-	runnerType   string
-	imageUrl     string
-	buildStatus  string
-	artifactPath string
-	maxAge       string
-	imagePattern string
+	runnerType      string
+	imageUrl        string
+	buildStatus     string
+	artifactPath    string
+	maxAge          string
+	imagePatterns   []string
+	keepLast        int
+	containerEngine string
+	loaderName      string
+	kindCluster     string
 )
 
 // imageLoadCmd represents the image load command
 var imageLoadCmd = &cobra.Command{
 	Use:   "load",
 	Short: "Load container image based on runner type and build status",
-	Long: `Load a container image in the appropriate way based on the runner type (self-hosted vs GitHub Actions)
+	Long: `Load a container image in the appropriate way based on the runner type (self-hosted vs GitHub-hosted)
 and the build status (locally built vs existing remote image).
 
-For self-hosted runners with locally built images, it verifies the image exists in the local Docker registry.
-For GitHub Actions with locally built images, it loads the image from artifacts.
-For existing remote images, it assumes the image is already available.`,
+The runner type is detected from the GITHUB_ACTIONS and RUNNER_ENVIRONMENT environment variables,
+use --runner to override it.
+
+For self-hosted runners and local workstations with locally built images, it verifies the image exists in the container runtime.
+For GitHub-hosted runners with locally built images, it loads the image from the artifact,
+which can be a docker or OCI tarball, or an OCI layout directory.
+For existing remote images, it assumes the image is already available.
+
+The container runtime is selected with --loader:
+- auto: first client found in PATH among docker, podman and ctr
+- docker, podman: image store of the container engine
+- containerd: containerd image store, in the k8s.io namespace, using ctr
+- kind: containerd image store of all the nodes of a kind cluster, using docker or podman exec`,
 	Example: `# Load image in GitHub Actions workflow
-ciux image load --runner "ubuntu-latest" --image-url "registry.io/project:v1.0.0" --build-status "true"
+ciux image load --image-url "registry.io/project:v1.0.0" --build-status "true"
+
+# Load image from an OCI layout directory into the nodes of a kind cluster
+ciux image load --loader kind --kind-cluster ci --artifact-path artifacts/image --image-url "registry.io/project:v1.0.0" --build-status "true"
 
 # Load image on self-hosted runner
-ciux image load --runner "['self-hosted']" --image-url "registry.io/project:v1.0.0" --build-status "false"`,
+ciux image load --runner self-hosted --image-url "registry.io/project:v1.0.0" --build-status "false"`,
 	Run: func(cmd *cobra.Command, args []string) {
 		err := runImageLoad()
 		internal.FailOnError(err)
@@ -74,15 +91,26 @@ var imageCleanupCmd = &cobra.Command{
 This is particularly useful for self-hosted runners to prevent disk space accumulation.
 
 The command removes:
-1. Images matching the specified pattern that are older than max-age
+1. Images matching one of the specified patterns that are older than max-age,
+   except the keep-last most recent images of each repository
 2. Dangling images (images not tagged or referenced)
 
-For safety, only images matching the pattern are removed, not all images.`,
+A pattern is matched against repository:tag, it can be:
+- a regular expression, prefixed with 'regex:'
+- a glob, if it contains '*', '?' or '[', where '*' does not match '/' and '**' matches anything
+- a substring otherwise
+
+For safety, only images matching the patterns are removed, not all images,
+and images referenced by the CIUXCONFIG file (variables ending with _IMAGE or _IMAGE_URL, and CIUX_IMAGE_<NAME>_URL) are never removed.
+Use --dry-run to list the images which would be removed and the reclaimed size.`,
 	Example: `# Clean up fink-broker images older than 5 days
 ciux image cleanup --max-age 5d --pattern fink-broker
 
-# Clean up all images matching pattern older than 1 week
-ciux image cleanup --max-age 1w --pattern "gitlab-registry.in2p3.fr/astrolabsoftware"`,
+# Clean up all images of a registry older than 1 week, keeping the 3 most recent images of each repository
+ciux image cleanup --max-age 1w --keep-last 3 --pattern "gitlab-registry.in2p3.fr/astrolabsoftware/**"
+
+# List the untested images which would be removed
+ciux image cleanup --dry-run --max-age 0d --pattern "regex:-untested$"`,
 	Run: func(cmd *cobra.Command, args []string) {
 		err := runImageCleanup()
 		internal.FailOnError(err)
@@ -95,109 +123,84 @@ func init() {
 	imageManagementCmd.AddCommand(imageCleanupCmd)
 
 	// Flags for the load subcommand
-	imageLoadCmd.Flags().StringVarP(&runnerType, "runner", "r", "", "Runner type (e.g., 'ubuntu-latest' or \"['self-hosted']\")")
+	imageLoadCmd.Flags().StringVarP(&runnerType, "runner", "r", string(internal.RunnerAuto), "Runner type ('auto', 'github-hosted', 'self-hosted' or 'local')")
 	imageLoadCmd.Flags().StringVarP(&imageUrl, "image-url", "i", "", "Container image URL")
 	imageLoadCmd.Flags().StringVarP(&buildStatus, "build-status", "b", "", "Build status ('true' if locally built, 'false' if existing)")
-	imageLoadCmd.Flags().StringVarP(&artifactPath, "artifact-path", "a", "artifacts/image.tar", "Path to artifact file for GitHub Actions")
+	imageLoadCmd.Flags().StringVarP(&artifactPath, "artifact-path", "a", "artifacts/image.tar", "Path to artifact file or OCI layout directory for GitHub Actions")
+	imageLoadCmd.Flags().StringVarP(&loaderName, "loader", "l", internal.LoaderAuto, fmt.Sprintf("Container runtime where the image is loaded (%s)", strings.Join(internal.ImageLoaders(), ", ")))
+	imageLoadCmd.Flags().StringVar(&kindCluster, "kind-cluster", "kind", "Name of the kind cluster, for the kind loader")
 
-	imageLoadCmd.MarkFlagRequired("runner")
 	imageLoadCmd.MarkFlagRequired("image-url")
 	imageLoadCmd.MarkFlagRequired("build-status")
 
 	// Flags for the cleanup subcommand
 	imageCleanupCmd.Flags().StringVarP(&maxAge, "max-age", "m", "5d", "Maximum age for images (e.g., '5d', '1w', '2h')")
-	imageCleanupCmd.Flags().StringVarP(&imagePattern, "pattern", "p", "", "Pattern to match image names (required)")
+	imageCleanupCmd.Flags().StringArrayVarP(&imagePatterns, "pattern", "p", []string{}, "Pattern to match image names, can be repeated (required)")
+	imageCleanupCmd.Flags().IntVarP(&keepLast, "keep-last", "k", 0, "Number of most recent images to keep for each repository")
+	imageCleanupCmd.Flags().StringVarP(&containerEngine, "engine", "e", internal.LoaderDocker, "Container engine (docker or podman)")
 
 	imageCleanupCmd.MarkFlagRequired("pattern")
 }
 
 func runImageLoad() error {
-	internal.Infof("Loading image: %s (build-status: %s, runner: %s)", imageUrl, buildStatus, runnerType)
+	runner, err := internal.ParseRunner(runnerType)
+	if err != nil {
+		return err
+	}
+	internal.Infof("Loading image: %s (build-status: %s, runner: %s)", imageUrl, buildStatus, runner)
 
 	// Parse build status
 	isLocallyBuilt := strings.ToLower(buildStatus) == "true"
 
-	// Determine if this is a self-hosted runner
-	isSelfHosted := strings.Contains(runnerType, "self-hosted")
-
 	if !isLocallyBuilt {
 		// Image already exists (remote), nothing to load
 		internal.Infof("Using existing remote image: %s", imageUrl)
 		return nil
 	}
 
-	// Image was built locally, handle based on runner type
-	if isSelfHosted {
-		return loadImageSelfHosted()
-	} else {
-		return loadImageGitHubActions()
-	}
-}
-
-func loadImageSelfHosted() error {
-	internal.Infof("Self-hosted runner: verifying image %s in local Docker registry", imageUrl)
-
-	// Check if image exists in local Docker registry
-	cmd := exec.Command("docker", "images", "--filter", fmt.Sprintf("reference=%s", imageUrl), "--format", "{{.Repository}}:{{.Tag}}")
-	output, err := cmd.Output()
+	loader, err := internal.NewImageLoader(loaderName, kindCluster)
 	if err != nil {
-		return fmt.Errorf("failed to check Docker images: %v", err)
+		return err
 	}
 
-	// Check if our image is in the output
-	if strings.Contains(string(output), imageUrl) {
-		internal.Infof("✓ Image %s found in local Docker registry", imageUrl)
-
-		// Show image details for verification
-		showCmd := exec.Command("docker", "images", "--filter", fmt.Sprintf("reference=%s", imageUrl))
-		showOutput, err := showCmd.Output()
-		if err != nil {
-			internal.Warnf("Could not show image details: %v", err)
-		} else {
-			fmt.Print(string(showOutput))
-		}
-
-		return nil
+	// Image was built locally, handle based on runner type
+	if runner == internal.RunnerGitHubHosted {
+		return loadImageFromArtifact(loader)
 	}
+	return checkLocalImage(loader)
+}
 
-	// Image not found - this is an error
-	internal.Infof("✗ Image %s not found in local Docker registry", imageUrl)
-	internal.Infof("Available images:")
+// checkLocalImage verifies that an image built by a previous job of a persistent runner is available in the container runtime
+func checkLocalImage(loader internal.ImageLoader) error {
+	internal.Infof("Verifying image %s in %s image store", imageUrl, loader.Name())
 
-	listCmd := exec.Command("docker", "images")
-	listOutput, err := listCmd.Output()
+	found, err := loader.Exists(imageUrl)
 	if err != nil {
-		internal.Warnf("Could not list Docker images: %v", err)
-	} else {
-		fmt.Print(string(listOutput))
+		return fmt.Errorf("failed to check image %s: %v", imageUrl, err)
 	}
-
-	return fmt.Errorf("image %s not found in local Docker registry", imageUrl)
+	if !found {
+		internal.Infof("✗ Image %s not found in %s image store", imageUrl, loader.Name())
+		return fmt.Errorf("image %s not found in %s image store", imageUrl, loader.Name())
+	}
+	internal.Infof("✓ Image %s found in %s image store", imageUrl, loader.Name())
+	return nil
 }
 
-func loadImageGitHubActions() error {
-	internal.Infof("GitHub Actions runner: loading image from artifact %s", artifactPath)
+// loadImageFromArtifact loads an image built by a previous job and shared as an artifact
+func loadImageFromArtifact(loader internal.ImageLoader) error {
+	internal.Infof("Loading image from artifact %s with %s loader", artifactPath, loader.Name())
 
-	// Check if artifact file exists
-	if _, err := os.Stat(artifactPath); os.IsNotExist(err) {
-		return fmt.Errorf("artifact file %s not found", artifactPath)
-	}
-
-	// Load image from tar archive
-	cmd := exec.Command("docker", "load", "--input", artifactPath)
-	output, err := cmd.CombinedOutput()
+	err := loader.Load(imageUrl, artifactPath)
 	if err != nil {
-		return fmt.Errorf("failed to load image from %s: %v\nOutput: %s", artifactPath, err, string(output))
+		return fmt.Errorf("failed to load image from %s: %v", artifactPath, err)
 	}
 
 	internal.Infof("✓ Successfully loaded image from %s", artifactPath)
-	fmt.Print(string(output))
-
 	return nil
 }
 
 func runImageCleanup() error {
-	internal.Infof("Cleaning up images matching pattern '%s' older than %s", imagePattern, maxAge)
+	internal.Infof("Cleaning up images matching pattern(s) '%s' older than %s, keeping the last %d of each repository", strings.Join(imagePatterns, "', '"), maxAge, keepLast)
 
 	// Parse max age duration
 	duration, err := parseMaxAge(maxAge)
@@ -205,69 +208,58 @@ func runImageCleanup() error {
 		return fmt.Errorf("invalid max-age format: %v", err)
 	}
 
-	cutoffTime := time.Now().Add(-duration)
-	internal.Infof("Removing images created before: %s", cutoffTime.Format("2006-01-02 15:04:05"))
-
-	// Get list of images with their creation time
-	cmd := exec.Command("docker", "images", "--format", "table {{.Repository}}\t{{.Tag}}\t{{.CreatedAt}}\t{{.ID}}")
-	output, err := cmd.Output()
+	policy := internal.CleanupPolicy{
+		MaxAge:   duration,
+		KeepLast: keepLast,
+	}
+	for _, p := range imagePatterns {
+		pattern, err := internal.NewImagePattern(p)
+		if err != nil {
+			return err
+		}
+		policy.Patterns = append(policy.Patterns, pattern)
+	}
+	policy.Protected, err = internal.GetProtectedImages(os.Getenv("CIUXCONFIG"))
 	if err != nil {
-		return fmt.Errorf("failed to list Docker images: %v", err)
+		return fmt.Errorf("unable to read images referenced by CIUXCONFIG: %v", err)
 	}
 
-	lines := strings.Split(string(output), "\n")
-	if len(lines) < 2 {
-		internal.Infof("No images found")
-		return nil
+	images, err := internal.ListLocalImages(containerEngine)
+	if err != nil {
+		return err
 	}
+	decisions := internal.PlanCleanup(images, policy, time.Now())
+	reclaimed := internal.FormatSize(internal.ReclaimedSize(images, decisions))
 
-	// Skip header line
 	var removedCount int
-	for _, line := range lines[1:] {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-
-		parts := strings.Fields(line)
-		if len(parts) < 4 {
-			continue
-		}
-
-		repo := parts[0]
-		tag := parts[1]
-		createdStr := strings.Join(parts[2:len(parts)-1], " ") // Created time might have spaces
-		imageId := parts[len(parts)-1]
-
-		// Check if image matches pattern
-		imageName := repo + ":" + tag
-		if !strings.Contains(imageName, imagePattern) {
+	for _, d := range decisions {
+		created := d.Image.Created.Format("2006-01-02 15:04:05")
+		if !d.Remove {
+			internal.Infof("Keeping image: %s (created: %s, %s)", d.Image.Name(), created, d.Reason)
 			continue
 		}
-
-		// Parse creation time
-		createdTime, err := parseDockerTime(createdStr)
-		if err != nil {
-			internal.Warnf("Could not parse creation time '%s' for image %s: %v", createdStr, imageName, err)
+		if dryRun {
+			internal.Infof("Dry run: remove image %s (created: %s, size: %s)", d.Image.Name(), created, internal.FormatSize(d.Image.Size))
+			removedCount++
 			continue
 		}
-
-		if createdTime.Before(cutoffTime) {
-			internal.Infof("Removing old image: %s (created: %s)", imageName, createdStr)
-			removeCmd := exec.Command("docker", "rmi", imageId)
-			if removeOutput, err := removeCmd.CombinedOutput(); err != nil {
-				internal.Warnf("Failed to remove image %s: %v\nOutput: %s", imageId, err, string(removeOutput))
-			} else {
-				internal.Infof("✓ Removed image %s", imageName)
-				removedCount++
-			}
+		internal.Infof("Removing old image: %s (created: %s, size: %s)", d.Image.Name(), created, internal.FormatSize(d.Image.Size))
+		if err := internal.RemoveLocalImage(containerEngine, d.Image); err != nil {
+			internal.Warnf("Failed to remove image %s: %v", d.Image.Name(), err)
 		} else {
-			internal.Infof("Keeping recent image: %s (created: %s)", imageName, createdStr)
+			internal.Infof("✓ Removed image %s", d.Image.Name())
+			removedCount++
 		}
 	}
 
+	if dryRun {
+		internal.Infof("Dry run: %d images would be removed, reclaiming %s", removedCount, reclaimed)
+		return nil
+	}
+
 	// Clean up dangling images
 	internal.Infof("Cleaning up dangling images")
-	pruneCmd := exec.Command("docker", "image", "prune", "-f")
+	pruneCmd := exec.Command(containerEngine, "image", "prune", "-f")
 	pruneOutput, err := pruneCmd.CombinedOutput()
 	if err != nil {
 		internal.Warnf("Failed to prune dangling images: %v\nOutput: %s", err, string(pruneOutput))
@@ -276,29 +268,7 @@ func runImageCleanup() error {
 		fmt.Print(string(pruneOutput))
 	}
 
-	// Show remaining images matching pattern
-	internal.Infof("Remaining images matching pattern '%s':", imagePattern)
-	listCmd := exec.Command("docker", "images")
-	listOutput, err := listCmd.Output()
-	if err != nil {
-		internal.Warnf("Could not list remaining images: %v", err)
-	} else {
-		found := false
-		for _, line := range strings.Split(string(listOutput), "\n") {
-			if strings.Contains(line, imagePattern) {
-				if !found {
-					fmt.Println("IMAGE\t\t\t\tTAG\t\tIMAGE ID\t\tCREATED\t\tSIZE")
-					found = true
-				}
-				fmt.Println(line)
-			}
-		}
-		if !found {
-			internal.Infof("No images matching pattern '%s' found", imagePattern)
-		}
-	}
-
-	internal.Infof("Cleanup completed: removed %d images", removedCount)
+	internal.Infof("Cleanup completed: removed %d images, reclaiming %s", removedCount, reclaimed)
 	return nil
 }
 
@@ -328,89 +298,3 @@ func parseMaxAge(maxAge string) (time.Duration, error) {
 		return 0, fmt.Errorf("unsupported unit '%s' (use d, h, w, m)", unit)
 	}
 }
-
-func parseDockerTime(timeStr string) (time.Time, error) {
-	// Docker time formats can vary, try common formats
-	formats := []string{
-		"2006-01-02 15:04:05 -0700 MST",
-		"2006-01-02 15:04:05 -0700",
-		"2006-01-02 15:04:05",
-		"2 days ago",
-		"3 weeks ago",
-		"1 month ago",
-	}
-
-	for _, format := range formats {
-		if t, err := time.Parse(format, timeStr); err == nil {
-			return t, nil
-		}
-	}
-
-	// Handle relative time strings
-	if strings.Contains(timeStr, "ago") {
-		return parseDockerRelativeTime(timeStr)
-	}
-
-	return time.Time{}, fmt.Errorf("unsupported time format: %s", timeStr)
-}
-
-func parseDockerRelativeTime(timeStr string) (time.Time, error) {
-	now := time.Now()
-
-	if strings.Contains(timeStr, "second") {
-		// Extract number of seconds
-		fields := strings.Fields(timeStr)
-		if len(fields) >= 1 {
-			if value, err := strconv.Atoi(fields[0]); err == nil {
-				return now.Add(-time.Duration(value) * time.Second), nil
-			}
-		}
-	}
-
-	if strings.Contains(timeStr, "minute") {
-		fields := strings.Fields(timeStr)
-		if len(fields) >= 1 {
-			if value, err := strconv.Atoi(fields[0]); err == nil {
-				return now.Add(-time.Duration(value) * time.Minute), nil
-			}
-		}
-	}
-
-	if strings.Contains(timeStr, "hour") {
-		fields := strings.Fields(timeStr)
-		if len(fields) >= 1 {
-			if value, err := strconv.Atoi(fields[0]); err == nil {
-				return now.Add(-time.Duration(value) * time.Hour), nil
-			}
-		}
-	}
-
-	if strings.Contains(timeStr, "day") {
-		fields := strings.Fields(timeStr)
-		if len(fields) >= 1 {
-			if value, err := strconv.Atoi(fields[0]); err == nil {
-				return now.Add(-time.Duration(value) * 24 * time.Hour), nil
-			}
-		}
-	}
-
-	if strings.Contains(timeStr, "week") {
-		fields := strings.Fields(timeStr)
-		if len(fields) >= 1 {
-			if value, err := strconv.Atoi(fields[0]); err == nil {
-				return now.Add(-time.Duration(value) * 7 * 24 * time.Hour), nil
-			}
-		}
-	}
-
-	if strings.Contains(timeStr, "month") {
-		fields := strings.Fields(timeStr)
-		if len(fields) >= 1 {
-			if value, err := strconv.Atoi(fields[0]); err == nil {
-				return now.Add(-time.Duration(value) * 30 * 24 * time.Hour), nil
-			}
-		}
-	}
-
-	return time.Time{}, fmt.Errorf("unsupported relative time: %s", timeStr)
-}
\ No newline at end of file
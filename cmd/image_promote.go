@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"github.com/k8s-school/ciux/cmd/util"
+	"github.com/k8s-school/ciux/internal"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// imagePromoteCmd represents the image promote command
+var imagePromoteCmd = &cobra.Command{
+	Use:   "promote repository_path",
+	Short: "Promote the container image tested by the CI",
+	Long: `Copy the container image tested by the CI (CIUX_IMAGE_URL) to the promoted image (CIUX_PROMOTED_IMAGE_URL),
+registry-to-registry, without using a container engine.
+Image urls are read from the CIUXCONFIG file for the label selector if it exists, else they are computed from the project state.
+Promotion is only performed for a clean worktree on main/master branch, use --branch for CI runners which checkout a single commit.`,
+	Example: `# Promote the image once e2e tests are successful
+ciux image promote --selector itest --branch "$GITHUB_REF_NAME" <path_to_git_repository>
+
+# Only print the image which would be promoted
+ciux image promote --dry-run --suffix noscience <path_to_git_repository>`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repositoryPath := internal.AbsPath(args[0])
+		project, _, err := internal.NewCoreProject(repositoryPath, branch)
+		internal.FailOnError(err)
+		project.TemporaryRegistry = tmpRegistry
+		project.ConfigFormat = configFormat
+		project.Selector, err = labels.Parse(labelSelector)
+		internal.FailOnError(err)
+
+		src, dst, err := project.GetPromotionUrls(suffix)
+		internal.FailOnError(err)
+		msg, err := project.PromoteImage(src, dst, dryRun)
+		internal.FailOnError(err)
+		internal.Infof("%s", msg)
+	},
+}
+
+func init() {
+	imageManagementCmd.AddCommand(imagePromoteCmd)
+
+	imagePromoteCmd.Flags().StringVarP(&branch, "branch", "b", "", "current branch for the project, retrieved from git if not specified")
+	imagePromoteCmd.Flags().StringVarP(&suffix, "suffix", "p", "", "Suffix to add to the image name")
+	imagePromoteCmd.Flags().StringVarP(&tmpRegistry, "tmp-registry", "t", "", "Name of temporary registry used to store the image during the ci process")
+	imagePromoteCmd.Flags().StringVarP(&configFormat, "format", "o", internal.DefaultConfigFormat, "Format of the CIUXCONFIG file")
+
+	util.AddLabelSelectorFlagVar(imagePromoteCmd, &labelSelector)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2025 Fabrice Jammes fabrice.jammes@in2p3.fr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/k8s-school/ciux/internal"
+	"github.com/spf13/cobra"
+)
+
+var pruneMaxAge string
+
+// imagePruneRegistryCmd represents the image prune-registry command
+var imagePruneRegistryCmd = &cobra.Command{
+	Use:   "prune-registry repository_path",
+	Short: "Delete stale untested tags of the project image in the registry",
+	Long: `Delete the untested tags (vXXX-untested) of the project image in the registry,
+and in the temporary registry if provided.
+
+An untested tag is deleted if:
+1. its tested counterpart (vXXX) now exists in the registry
+2. or it is older than max-age, use '0d' to disable this check
+
+The tag reference is deleted when the registry supports it. Otherwise, e.g. with registry:2,
+the manifest is deleted by digest, unless another tag shares it, e.g. the tested tag copied
+by 'ciux image promote': such untested tags are reported as undeletable and the command fails.
+
+Untested tags are produced by 'ciux ignite' when 'untestedTags: true' is set in the .ciux file.`,
+	Example: `# Delete untested tags which have been promoted or are older than one week
+ciux image prune-registry --max-age 1w <path_to_git_repository>
+
+# Only print the tags which would be deleted
+ciux image prune-registry --dry-run --suffix noscience <path_to_git_repository>`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repositoryPath := internal.AbsPath(args[0])
+		project, _, err := internal.NewCoreProject(repositoryPath, "")
+		internal.FailOnError(err)
+		project.TemporaryRegistry = tmpRegistry
+
+		duration, err := parseMaxAge(pruneMaxAge)
+		if err != nil {
+			internal.FailOnError(fmt.Errorf("invalid max-age format: %v", err))
+		}
+		repositories, err := project.GetImageRepositories(suffix)
+		internal.FailOnError(err)
+		for _, repository := range repositories {
+			deleted, err := internal.PruneUntestedTags(repository, duration, dryRun)
+			verb := "deleted"
+			if dryRun {
+				verb = "to delete"
+			}
+			internal.Infof("Repository %s: %d untested tag(s) %s", repository, len(deleted), verb)
+			for _, url := range deleted {
+				internal.Infof("  %s", url)
+			}
+			// Undeletable tags are reported after the deleted ones
+			internal.FailOnError(err)
+		}
+	},
+}
+
+func init() {
+	imageManagementCmd.AddCommand(imagePruneRegistryCmd)
+
+	imagePruneRegistryCmd.Flags().StringVarP(&pruneMaxAge, "max-age", "m", "2w", "Maximum age for untested tags (e.g., '5d', '1w', '2h')")
+	imagePruneRegistryCmd.Flags().StringVarP(&suffix, "suffix", "p", "", "Suffix to add to the image name")
+	imagePruneRegistryCmd.Flags().StringVarP(&tmpRegistry, "tmp-registry", "t", "", "Name of temporary registry used to store the image during the ci process")
+}
@@ -0,0 +1,83 @@
+/*
+Copyright © 2025 Fabrice Jammes fabrice.jammes@in2p3.fr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/k8s-school/ciux/internal"
+	"github.com/spf13/cobra"
+)
+
+var retentionPolicy internal.RetentionPolicy
+
+// imageRetentionCmd represents the image retention command
+var imageRetentionCmd = &cobra.Command{
+	Use:   "retention repository_path",
+	Short: "Apply a retention policy to the tags of the project image in the registry",
+	Long: `List the tags of the project image in the registry, parse them back to git revisions
+and delete the ones which are not kept by the retention policy:
+1. release tags (vX.Y.Z) are kept, unless --keep-releases=false
+2. the images of the last N commits of each branch are kept, see --keep-last
+3. the tags whose commit is not reachable from any branch are deleted, unless --delete-unreachable=false
+
+Tags which are not produced by ciux (e.g. 'latest') are always kept.
+Remote-tracking branches are used if they exist, local branches otherwise,
+so the repository must contain the full git history, i.e. 'git fetch --unshallow' on CI runners.
+
+The retention report is always printed, use --dry-run to only print it.`,
+	Example: `# Print the tags which would be deleted
+ciux image retention --dry-run --keep-last 10 <path_to_git_repository>
+
+# Delete the tags, except the releases and the last 10 images of each branch
+ciux image retention --keep-last 10 <path_to_git_repository>`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repositoryPath := internal.AbsPath(args[0])
+		project, _, err := internal.NewCoreProject(repositoryPath, "")
+		internal.FailOnError(err)
+		project.TemporaryRegistry = tmpRegistry
+
+		if retentionPolicy.KeepLast < 0 {
+			internal.FailOnError(fmt.Errorf("invalid keep-last value: %d", retentionPolicy.KeepLast))
+		}
+		repositories, err := project.GetImageRepositories(suffix)
+		internal.FailOnError(err)
+		for _, repository := range repositories {
+			report, err := internal.PlanRegistryRetention(repository, project.GitMain, retentionPolicy)
+			internal.FailOnError(err)
+			internal.Infof("%s", report)
+			if dryRun {
+				internal.Infof("Dry run: %d tag(s) to delete", len(report.ToDelete()))
+				continue
+			}
+			err = report.ApplyRetention()
+			internal.FailOnError(err)
+			internal.Infof("%d tag(s) deleted", len(report.ToDelete()))
+		}
+	},
+}
+
+func init() {
+	imageManagementCmd.AddCommand(imageRetentionCmd)
+
+	imageRetentionCmd.Flags().BoolVar(&retentionPolicy.KeepReleases, "keep-releases", true, "Keep the release tags")
+	imageRetentionCmd.Flags().IntVarP(&retentionPolicy.KeepLast, "keep-last", "k", 10, "Number of images to keep for each branch, 0 means no limit")
+	imageRetentionCmd.Flags().BoolVar(&retentionPolicy.DeleteUnreachable, "delete-unreachable", true, "Delete the tags whose commit is not reachable from any branch")
+	imageRetentionCmd.Flags().StringVarP(&suffix, "suffix", "p", "", "Suffix to add to the image name")
+	imageRetentionCmd.Flags().StringVarP(&tmpRegistry, "tmp-registry", "t", "", "Name of temporary registry used to store the image during the ci process")
+}
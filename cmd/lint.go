@@ -0,0 +1,63 @@
+/*
+Copyright © 2025 Fabrice Jammes fabrice.jammes@in2p3.fr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/k8s-school/ciux/internal"
+	"github.com/spf13/cobra"
+)
+
+var strict bool
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint repository_path",
+	Short: "Check the .ciux configuration file",
+	Long: `Check the repository_path/.ciux configuration file, and warn when the sourcePathes of an image
+and the sources of the COPY/ADD instructions of its Dockerfile disagree:
+- a Dockerfile source which is not in sourcePathes does not trigger a rebuild when it changes
+- a source path which is not copied by the Dockerfile triggers a useless rebuild when it changes
+Images whose source pathes are computed with 'autoSourcePathes: Dockerfile' are not checked.`,
+	Example: `# Check the .ciux file, and exit with error if there are warnings
+ciux lint --strict <path_to_git_repository>`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repositoryPath := internal.AbsPath(args[0])
+		project, _, err := internal.NewCoreProject(repositoryPath, "")
+		internal.FailOnError(err)
+
+		warnings, err := project.LintSourcePathes()
+		internal.FailOnError(err)
+		for _, warning := range warnings {
+			internal.Warnf("warning: %s", warning)
+		}
+		if strict && len(warnings) != 0 {
+			internal.FailOnError(fmt.Errorf("%d warning(s) found", len(warnings)))
+		}
+		if len(warnings) == 0 {
+			internal.Infof("No warning found")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().BoolVar(&strict, "strict", false, "Exit with error if there are warnings")
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/k8s-school/ciux/cmd/util"
+	"github.com/k8s-school/ciux/internal"
+	"github.com/spf13/cobra"
+)
+
+var lockFile string
+
+// replayCmd represents the replay command
+var replayCmd = &cobra.Command{
+	Use:   "replay repository_path",
+	Short: "Prepare integration test from a lock file",
+	Long: `Reproduce a previous 'ciux ignite' run from the lock file it has written.
+	Dependencies are cloned at the exact commit recorded in the lock file, instead of the head of their work branch,
+	Go packages are installed at the recorded version and dependencies images digests are checked against the recorded ones.
+	The command fails if a recorded commit or image is no longer reachable,
+	the recorded commit of a remote-only dependency must still exist even if its branch has moved.
+	The project main repository must be checked out at the recorded commit.
+	`,
+	Example: `# Replay the CI run for the 'itest' selector
+ciux replay --selector itest <path_to_git_repository>
+
+# Replay the CI run recorded in a given lock file
+ciux replay --lock-file ciux_itest.lock.yaml <path_to_git_repository>`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		repositoryPath := internal.AbsPath(args[0])
+
+		project, lock, err := internal.NewReplayProject(repositoryPath, lockFile, labelSelector)
+		internal.FailOnError(err)
+		project.ConfigFormat = configFormat
+		depsBasePath := filepath.Dir(repositoryPath)
+
+		// Retrieve dependencies sources at the recorded commit
+		err = project.RetrieveLockedDepsSources(depsBasePath)
+		internal.FailOnError(err)
+
+		// Install dependencies Go modules
+		goMsg, err := project.InstallGoModules()
+		internal.FailOnError(err)
+
+		// Check if dependencies container images still have the recorded digest
+		images, err := internal.CheckLockedImages(lock)
+		internal.FailOnError(err)
+
+		internal.Infof("%s", project.String())
+		internal.Infof("Image:\n%s", project.Image)
+
+		goMsg = strings.TrimRight(goMsg, "\n")
+		internal.Infof("Go modules installed:\n%s", goMsg)
+
+		var imgMsg string
+		for _, image := range images {
+			imgMsg += "  " + image.Name() + "\n"
+		}
+		imgMsg = strings.TrimRight(imgMsg, "\n")
+		internal.Infof("Available Images for dependencies:\n%s", imgMsg)
+
+		// Write project configuration file
+		msg, err := project.WriteOutConfig()
+		internal.FailOnError(err)
+		internal.Infof("%s", msg)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().StringVarP(&configFormat, "format", "o", internal.DefaultConfigFormat, "Format of the CIUXCONFIG file, one of: "+strings.Join(internal.ConfigFormats(), ", "))
+	replayCmd.Flags().StringVarP(&lockFile, "lock-file", "f", "", "Path to the lock file, default to the lock file written by 'ciux ignite' for the label selector")
+
+	util.AddLabelSelectorFlagVar(replayCmd, &labelSelector)
+}
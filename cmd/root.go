@@ -19,6 +19,7 @@ package cmd
 import (
 	"os"
 
+	"github.com/k8s-school/ciux/internal"
 	"github.com/k8s-school/ciux/log"
 	"github.com/spf13/cobra"
 )
@@ -27,6 +28,8 @@ var (
 	dryRun        bool
 	verbosity     int
 	labelSelector string
+	// Registries reached over plain HTTP or with unverified TLS certificates
+	insecureRegistryHosts []string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -51,12 +54,18 @@ func Execute() {
 func init() {
 	rootCmd.PersistentFlags().IntVarP(&verbosity, "verbosity", "v", 0, "Verbosity level (-v0 for minimal, -v2 for maximum)")
 
-	cobra.OnInitialize(initLogger)
+	cobra.OnInitialize(initLogger, initRegistries)
 
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Only print the command")
+	rootCmd.PersistentFlags().StringSliceVar(&insecureRegistryHosts, "insecure-registry", []string{}, "Registry reached over plain HTTP or with unverified TLS certificates, e.g. localhost:5000, can be repeated")
 }
 
 // setUpLogs set the log output ans the log level
 func initLogger() {
 	log.Init(verbosity)
 }
+
+// initRegistries set the registries options
+func initRegistries() {
+	internal.SetInsecureRegistries(insecureRegistryHosts)
+}
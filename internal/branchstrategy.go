@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// DefaultBranchKeyword is the branch strategy entry for the default branch of a dependency repository,
+// i.e. the branch of the HEAD advertised by the remote, or main/master
+const DefaultBranchKeyword = "HEAD"
+
+// DefaultBranchStrategy is the branch strategy used when it is not set in the .ciux file:
+// the branch of the project if it exists in the dependency repository, or its default branch otherwise
+var DefaultBranchStrategy = []string{"{{.Branch}}", DefaultBranchKeyword}
+
+// BranchStrategyData is the data available in the templates of a branch strategy
+type BranchStrategyData struct {
+	// Work branch of the project
+	Branch string
+	// Major and minor versions of the latest semver tag of the project, empty if there is no semver tag,
+	// e.g. "release/{{.Major}}.x" is release/3.x for a project at v3.2.0-5-g1234567
+	Major string
+	Minor string
+}
+
+// validateBranchStrategy checks that the templates of a branch strategy are valid
+func validateBranchStrategy(strategy []string) error {
+	for _, entry := range strategy {
+		if _, err := renderBranchStrategyEntry(entry, BranchStrategyData{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderBranchStrategyEntry(entry string, data BranchStrategyData) (string, error) {
+	tmpl, err := template.New("branchStrategy").Parse(entry)
+	if err != nil {
+		return "", fmt.Errorf("invalid branch strategy entry %q: %v", entry, err)
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return "", fmt.Errorf("invalid branch strategy entry %q: %v", entry, err)
+	}
+	return buf.String(), nil
+}
+
+// getBranchStrategyData returns the data of the project for the branch strategy templates
+func (project *Project) getBranchStrategyData() BranchStrategyData {
+	data := BranchStrategyData{Branch: project.GitMain.WorkBranch}
+	rev, err := project.GitMain.GetHeadRevision()
+	if err != nil {
+		slog.Debug("Unable to describe project repository for branch strategy", "error", err)
+		return data
+	}
+	if v := SemVerParse(rev.Tag); v != nil {
+		data.Major = strconv.Itoa(v.Major)
+		data.Minor = strconv.Itoa(v.Minor)
+	}
+	return data
+}
+
+// getBranchStrategy returns the branch strategy of a dependency, which overrides the one of the project
+func (project *Project) getBranchStrategy(depStrategy []string) []string {
+	if len(depStrategy) != 0 {
+		return depStrategy
+	}
+	if len(project.Config.BranchStrategy) != 0 {
+		return project.Config.BranchStrategy
+	}
+	return DefaultBranchStrategy
+}
+
+// ResolveBranch returns the work branch of a remote repository, and the hash of its head,
+// for the first entry of a branch strategy which matches one of its branches.
+// Entries are templates rendered with BranchStrategyData, then:
+//   - HEAD is the default branch of the repository
+//   - a glob, e.g. release/*, matches the branches with path.Match, the one with the highest version numbers
+//     on the release line of the project is used, see releaseLineMatches
+//   - any other entry is the exact name of a branch
+//
+// LsRemote must have been called before
+func (gitObj *Git) ResolveBranch(strategy []string, data BranchStrategyData) (string, string, error) {
+	for _, entry := range strategy {
+		candidate, err := renderBranchStrategyEntry(entry, data)
+		if err != nil {
+			return "", "", err
+		}
+		if candidate == "" {
+			continue
+		}
+		if candidate == DefaultBranchKeyword {
+			return gitObj.MainBranch()
+		}
+		if strings.ContainsAny(candidate, "*?[") {
+			matches := []string{}
+			for _, branch := range gitObj.RemoteBranches {
+				if matched, err := path.Match(candidate, branch); err != nil {
+					return "", "", fmt.Errorf("invalid branch strategy entry %q: %v", entry, err)
+				} else if matched {
+					matches = append(matches, branch)
+				}
+			}
+			if len(matches) == 0 {
+				continue
+			}
+			matches = releaseLineMatches(candidate, matches, data)
+			sort.Slice(matches, func(i, j int) bool { return versionLess(matches[i], matches[j]) })
+			candidate = matches[len(matches)-1]
+		}
+		found, hash, err := gitObj.HasBranch(candidate)
+		if err != nil {
+			return "", "", err
+		}
+		if found {
+			slog.Debug("Branch strategy entry matched", "url", gitObj.Url, "entry", entry, "branch", candidate)
+			return candidate, hash, nil
+		}
+	}
+	return "", "", fmt.Errorf("no branch matches branch strategy %v for git repository %s", strategy, gitObj.Url)
+}
+
+// releaseLineMatches returns the branches matching a glob which are on the release line of the project,
+// i.e. whose version numbers, after the literal prefix of the glob, start with the project major and minor versions,
+// or only the major version, e.g. release/2.4 and then release/2.x for a project at v2.4.1.
+// All the branches are returned if none is on the release line.
+func releaseLineMatches(glob string, branches []string, data BranchStrategyData) []string {
+	if data.Major == "" {
+		return branches
+	}
+	prefix := glob[:strings.IndexAny(glob, "*?[")]
+	for _, line := range [][]string{{data.Major, data.Minor}, {data.Major}} {
+		if slices.Contains(line, "") {
+			continue
+		}
+		matches := []string{}
+		for _, branch := range branches {
+			numbers := digitSequences(strings.TrimPrefix(branch, prefix))
+			if len(numbers) >= len(line) && slices.Equal(numbers[:len(line)], line) {
+				matches = append(matches, branch)
+			}
+		}
+		if len(matches) != 0 {
+			return matches
+		}
+	}
+	return branches
+}
+
+// digitSequences returns the sequences of digits of a string, without their leading zeros
+func digitSequences(s string) []string {
+	numbers := []string{}
+	for s != "" {
+		digits := leadingDigits(s)
+		if digits == "" {
+			s = s[1:]
+			continue
+		}
+		n, _ := strconv.Atoi(digits)
+		numbers = append(numbers, strconv.Itoa(n))
+		s = s[len(digits):]
+	}
+	return numbers
+}
+
+// versionLess compares two strings, with their sequences of digits compared as numbers,
+// so that release/3.10 is greater than release/3.9
+func versionLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := leadingDigits(a), leadingDigits(b)
+		if da != "" && db != "" {
+			na, _ := strconv.Atoi(da)
+			nb, _ := strconv.Atoi(db)
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveBranch(t *testing.T) {
+	require := require.New(t)
+
+	gitDep, err := initGitRepo("ciux-branchstrategy-test-")
+	require.NoError(err)
+	depRoot, err := gitDep.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(depRoot)
+
+	_, _, err = gitDep.TaggedCommit("file1.txt", "commit1", "v1.0.0", true, author)
+	require.NoError(err)
+	for _, branch := range []string{"release/2.3", "release/2.4", "release/3.9", "release/3.10", "feature-x", "develop"} {
+		require.NoError(gitDep.CreateBranch(branch))
+	}
+	worktree, err := gitDep.Repository.Worktree()
+	require.NoError(err)
+	// The default branch of the dependency is the checked out one
+	err = worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("develop")})
+	require.NoError(err)
+
+	remoteDep := Git{Url: "file://" + depRoot}
+	require.NoError(remoteDep.LsRemote())
+	require.Equal("develop", remoteDep.RemoteHead)
+
+	tests := []struct {
+		strategy []string
+		data     BranchStrategyData
+		expected string
+	}{
+		{DefaultBranchStrategy, BranchStrategyData{Branch: "feature-x"}, "feature-x"},
+		{DefaultBranchStrategy, BranchStrategyData{Branch: "feature-y"}, "develop"},
+		{[]string{"{{.Branch}}", "release/{{.Major}}.*"}, BranchStrategyData{Branch: "feature-y", Major: "3"}, "release/3.10"},
+		{[]string{"release/{{.Major}}.*", "master"}, BranchStrategyData{Major: "4"}, "master"},
+		{[]string{"release/*"}, BranchStrategyData{}, "release/3.10"},
+		{[]string{"{{.Branch}}", "master"}, BranchStrategyData{}, "master"},
+		// Release line of the project
+		{[]string{"release/*"}, BranchStrategyData{Major: "2", Minor: "3"}, "release/2.3"},
+		{[]string{"release/*"}, BranchStrategyData{Major: "2", Minor: "9"}, "release/2.4"},
+		{[]string{"release/*"}, BranchStrategyData{Major: "3", Minor: "9"}, "release/3.9"},
+		{[]string{"release/*"}, BranchStrategyData{Major: "5", Minor: "0"}, "release/3.10"},
+	}
+	for _, tt := range tests {
+		branch, hash, err := remoteDep.ResolveBranch(tt.strategy, tt.data)
+		require.NoError(err, "strategy=%v", tt.strategy)
+		require.Equal(tt.expected, branch, "strategy=%v", tt.strategy)
+		require.NotEmpty(hash)
+	}
+
+	_, _, err = remoteDep.ResolveBranch([]string{"missing", "release/4.*"}, BranchStrategyData{})
+	require.Error(err)
+
+	// The strategy of a dependency overrides the one of the project
+	project := Project{}
+	require.Equal(DefaultBranchStrategy, project.getBranchStrategy(nil))
+	project.Config.BranchStrategy = []string{"develop"}
+	require.Equal([]string{"develop"}, project.getBranchStrategy(nil))
+	require.Equal([]string{"master"}, project.getBranchStrategy([]string{"master"}))
+
+	require.NoError(validateBranchStrategy([]string{"{{.Branch}}", "release/{{.Major}}.{{.Minor}}", "HEAD"}))
+	require.Error(validateBranchStrategy([]string{"{{.Unknown}}"}))
+	require.Error(validateBranchStrategy([]string{"{{.Branch"}))
+}
+
+func TestVersionLess(t *testing.T) {
+	require := require.New(t)
+
+	require.True(versionLess("release/3.9", "release/3.10"))
+	require.False(versionLess("release/3.10", "release/3.9"))
+	require.True(versionLess("release/2.10", "release/3.1"))
+	require.True(versionLess("release/3", "release/3.1"))
+	require.False(versionLess("release/3.1", "release/3.1"))
+}
+
+func TestReleaseLineMatches(t *testing.T) {
+	require := require.New(t)
+
+	branches := []string{"release/2.3", "release/2.4", "release/v3.09", "release/3.x", "release/13.0"}
+	require.Equal([]string{"release/2.4"}, releaseLineMatches("release/*", branches, BranchStrategyData{Major: "2", Minor: "4"}))
+	require.Equal([]string{"release/v3.09", "release/3.x"}, releaseLineMatches("release/*", branches, BranchStrategyData{Major: "3", Minor: "1"}))
+	require.Equal([]string{"release/v3.09"}, releaseLineMatches("release/*", branches, BranchStrategyData{Major: "3", Minor: "9"}))
+	require.Equal(branches, releaseLineMatches("release/*", branches, BranchStrategyData{Major: "4", Minor: "0"}))
+	require.Equal(branches, releaseLineMatches("release/*", branches, BranchStrategyData{Branch: "main"}))
+	// Digits of the literal prefix of the glob are ignored
+	require.Equal([]string{"py3/release/2.4"}, releaseLineMatches("py3/release/*", []string{"py3/release/2.4", "py3/release/3.0"}, BranchStrategyData{Major: "2"}))
+}
@@ -0,0 +1,302 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// LocalImage is a tagged image of the image store of a container engine
+type LocalImage struct {
+	ID         string
+	Repository string
+	Tag        string
+	Created    time.Time
+	// Size of the image, in bytes
+	Size int64
+}
+
+// Name returns the repository and tag of the image
+func (i LocalImage) Name() string {
+	return i.Repository + ":" + i.Tag
+}
+
+// inspectedImage is the subset of the output of 'docker image inspect' used by ciux
+type inspectedImage struct {
+	Id       string
+	RepoTags []string
+	Created  time.Time
+	Size     int64
+}
+
+// ListLocalImages returns the tagged images of the image store of a container engine (docker or podman),
+// an image with several tags is returned once per tag
+func ListLocalImages(engine string) ([]LocalImage, error) {
+	stdout, _, err := ExecCmdArgs(nil, false, engine, "image", "ls", "--quiet", "--no-trunc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %v", err)
+	}
+	ids := []string{}
+	for _, id := range strings.Fields(stdout) {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return []LocalImage{}, nil
+	}
+	stdout, _, err = ExecCmdArgs(nil, false, append([]string{engine, "image", "inspect"}, ids...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect images: %v", err)
+	}
+	return parseInspectedImages([]byte(stdout))
+}
+
+// parseInspectedImages parses the JSON output of 'docker image inspect'
+func parseInspectedImages(data []byte) ([]LocalImage, error) {
+	inspected := []inspectedImage{}
+	err := json.Unmarshal(data, &inspected)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse image inspect output: %v", err)
+	}
+	images := []LocalImage{}
+	for _, i := range inspected {
+		for _, repoTag := range i.RepoTags {
+			sep := strings.LastIndex(repoTag, ":")
+			if sep == -1 || strings.Contains(repoTag[sep:], "/") {
+				slog.Debug("Skip image with invalid tag", "id", i.Id, "tag", repoTag)
+				continue
+			}
+			images = append(images, LocalImage{
+				ID:         i.Id,
+				Repository: repoTag[:sep],
+				Tag:        repoTag[sep+1:],
+				Created:    i.Created,
+				Size:       i.Size,
+			})
+		}
+	}
+	return images, nil
+}
+
+// ImagePattern matches image names, i.e. repository:tag
+// it is a regular expression if it starts with 'regex:', a glob if it contains '*', '?' or '[',
+// or a substring otherwise
+type ImagePattern struct {
+	re *regexp.Regexp
+}
+
+// NewImagePattern returns a pattern for image names
+// in globs, '*' and '?' do not match '/', and '**' matches any sequence of characters
+func NewImagePattern(pattern string) (ImagePattern, error) {
+	var expr string
+	if strings.HasPrefix(pattern, "regex:") {
+		expr = strings.TrimPrefix(pattern, "regex:")
+	} else if strings.ContainsAny(pattern, "*?[") {
+		expr = "^" + globToRegexp(pattern) + "$"
+	} else {
+		expr = regexp.QuoteMeta(pattern)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return ImagePattern{}, fmt.Errorf("invalid image pattern %q: %v", pattern, err)
+	}
+	return ImagePattern{re: re}, nil
+}
+
+// Match returns true if an image name matches the pattern
+func (p ImagePattern) Match(imageName string) bool {
+	return p.re.MatchString(imageName)
+}
+
+func globToRegexp(glob string) string {
+	var expr strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				expr.WriteString(".*")
+				i++
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end == -1 {
+				expr.WriteString(regexp.QuoteMeta(string(c)))
+			} else {
+				class := glob[i+1 : i+end]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				expr.WriteString("[" + class + "]")
+				i += end
+			}
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return expr.String()
+}
+
+// CleanupPolicy describes which local images are removed
+type CleanupPolicy struct {
+	// Only images matching one of the patterns are removed
+	Patterns []ImagePattern
+	// Only images older than MaxAge are removed, 0 means no age limit
+	MaxAge time.Duration
+	// Number of most recent images kept for each repository
+	KeepLast int
+	// Images which are never removed, like the ones referenced by the CIUXCONFIG file
+	Protected []string
+}
+
+// CleanupDecision is the decision taken by the cleanup policy for a local image
+type CleanupDecision struct {
+	Image  LocalImage
+	Remove bool
+	Reason string
+}
+
+// PlanCleanup applies the cleanup policy to the images matching its patterns
+func PlanCleanup(images []LocalImage, policy CleanupPolicy, now time.Time) []CleanupDecision {
+	protected := map[string]bool{}
+	for _, p := range policy.Protected {
+		protected[normalizeImageName(p)] = true
+	}
+
+	byRepository := map[string][]LocalImage{}
+	repositories := []string{}
+	for _, image := range images {
+		matched := false
+		for _, pattern := range policy.Patterns {
+			if pattern.Match(image.Name()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if _, ok := byRepository[image.Repository]; !ok {
+			repositories = append(repositories, image.Repository)
+		}
+		byRepository[image.Repository] = append(byRepository[image.Repository], image)
+	}
+	sort.Strings(repositories)
+
+	decisions := []CleanupDecision{}
+	for _, repository := range repositories {
+		repoImages := byRepository[repository]
+		sort.SliceStable(repoImages, func(i, j int) bool {
+			return repoImages[i].Created.After(repoImages[j].Created)
+		})
+		for rank, image := range repoImages {
+			decision := CleanupDecision{Image: image}
+			age := now.Sub(image.Created)
+			if protected[normalizeImageName(image.Name())] {
+				decision.Reason = "referenced by CIUXCONFIG"
+			} else if rank < policy.KeepLast {
+				decision.Reason = fmt.Sprintf("last %d of repository", policy.KeepLast)
+			} else if policy.MaxAge > 0 && age <= policy.MaxAge {
+				decision.Reason = "recent"
+			} else {
+				decision.Remove = true
+				decision.Reason = fmt.Sprintf("created %s ago", age.Round(time.Minute))
+			}
+			decisions = append(decisions, decision)
+		}
+	}
+	return decisions
+}
+
+// ReclaimedSize returns the disk space freed by the removal of the images,
+// an image is only freed if all its tags, among all the local images, are removed
+func ReclaimedSize(images []LocalImage, decisions []CleanupDecision) int64 {
+	removed := map[string]bool{}
+	for _, d := range decisions {
+		if d.Remove {
+			removed[d.Image.Name()] = true
+		}
+	}
+	kept := map[string]bool{}
+	for _, image := range images {
+		if !removed[image.Name()] {
+			kept[image.ID] = true
+		}
+	}
+	counted := map[string]bool{}
+	var size int64
+	for _, d := range decisions {
+		if d.Remove && !kept[d.Image.ID] && !counted[d.Image.ID] {
+			counted[d.Image.ID] = true
+			size += d.Image.Size
+		}
+	}
+	return size
+}
+
+// RemoveLocalImage removes a tag from the image store of a container engine,
+// the image is removed with its last tag
+func RemoveLocalImage(engine string, image LocalImage) error {
+	_, _, err := ExecCmdArgs(nil, false, engine, "rmi", image.Name())
+	return err
+}
+
+// GetProtectedImages returns the images referenced by a CIUXCONFIG file,
+// i.e. the values of the image variables, see isImageConfigVar
+// it returns no image if the file does not exist
+func GetProtectedImages(ciuxConfigFilepath string) ([]string, error) {
+	images := []string{}
+	if ciuxConfigFilepath == "" {
+		return images, nil
+	}
+	if _, err := os.Stat(ciuxConfigFilepath); os.IsNotExist(err) {
+		return images, nil
+	}
+	vars, err := ReadConfigFile(ciuxConfigFilepath)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range vars {
+		if value != "" && isImageConfigVar(name) {
+			images = append(images, value)
+		}
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// FormatSize returns a human readable size
+func FormatSize(size int64) string {
+	const unit = 1000
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(size)/float64(div), "kMGTPE"[exp])
+}
+
+// normalizeImageName returns the fully qualified name of an image, so that 'alpine:3' and 'docker.io/library/alpine:3' are equal
+func normalizeImageName(imageName string) string {
+	ref, err := name.ParseReference(imageName)
+	if err != nil {
+		return imageName
+	}
+	return ref.Name()
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInspectedImages(t *testing.T) {
+	require := require.New(t)
+
+	data := `[
+  {"Id": "sha256:aaa", "RepoTags": ["registry.io/project:v1.0.0", "localhost:5000/project:latest"], "Created": "2024-01-02T15:04:05.123456789Z", "Size": 1000},
+  {"Id": "sha256:bbb", "RepoTags": [], "Created": "2024-01-01T00:00:00Z", "Size": 2000}
+]`
+	images, err := parseInspectedImages([]byte(data))
+	require.NoError(err)
+	require.Len(images, 2)
+	require.Equal("registry.io/project", images[0].Repository)
+	require.Equal("v1.0.0", images[0].Tag)
+	require.Equal("localhost:5000/project", images[1].Repository)
+	require.Equal("latest", images[1].Tag)
+	require.Equal(int64(1000), images[1].Size)
+	require.Equal(2024, images[1].Created.Year())
+
+	_, err = parseInspectedImages([]byte("REPOSITORY   TAG"))
+	require.Error(err)
+}
+
+func TestImagePattern(t *testing.T) {
+	require := require.New(t)
+
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"fink-broker", "registry.io/fink-broker:v1.0.0", true},
+		{"fink-broker", "registry.io/fink-alert:v1.0.0", false},
+		{"registry.io/*", "registry.io/fink-broker:v1.0.0", true},
+		{"registry.io/*", "registry.io/fink/broker:v1.0.0", false},
+		{"registry.io/**", "registry.io/fink/broker:v1.0.0", true},
+		{"registry.io/fink-broker:v1.?.0", "registry.io/fink-broker:v1.2.0", true},
+		{"*/fink-[ab]*", "registry.io/fink-broker:v1.0.0", true},
+		{"*/fink-[!ab]*", "registry.io/fink-broker:v1.0.0", false},
+		{"regex:-untested$", "registry.io/fink-broker:v1.0.0-untested", true},
+		{"regex:-untested$", "registry.io/fink-broker:v1.0.0", false},
+	}
+	for _, tt := range tests {
+		pattern, err := NewImagePattern(tt.pattern)
+		require.NoError(err)
+		require.Equal(tt.expected, pattern.Match(tt.name), "pattern %s, image %s", tt.pattern, tt.name)
+	}
+
+	_, err := NewImagePattern("regex:(")
+	require.Error(err)
+}
+
+func TestPlanCleanup(t *testing.T) {
+	require := require.New(t)
+	now := time.Now()
+	day := 24 * time.Hour
+
+	images := []LocalImage{
+		{ID: "1", Repository: "registry.io/project", Tag: "v1", Created: now.Add(-10 * day), Size: 100},
+		{ID: "2", Repository: "registry.io/project", Tag: "v2", Created: now.Add(-8 * day), Size: 200},
+		{ID: "3", Repository: "registry.io/project", Tag: "v3", Created: now.Add(-6 * day), Size: 300},
+		{ID: "4", Repository: "registry.io/project", Tag: "v4", Created: now.Add(-1 * day), Size: 400},
+		{ID: "2", Repository: "registry.io/project-tmp", Tag: "v2", Created: now.Add(-8 * day), Size: 200},
+		{ID: "5", Repository: "alpine", Tag: "3.19", Created: now.Add(-30 * day), Size: 500},
+		{ID: "6", Repository: "registry.io/other", Tag: "v1", Created: now.Add(-30 * day), Size: 600},
+	}
+	pattern, err := NewImagePattern("registry.io/project*")
+	require.NoError(err)
+	alpine, err := NewImagePattern("alpine")
+	require.NoError(err)
+	policy := CleanupPolicy{
+		Patterns:  []ImagePattern{pattern, alpine},
+		MaxAge:    5 * day,
+		KeepLast:  1,
+		Protected: []string{"registry.io/project:v1", "docker.io/library/alpine:3.19"},
+	}
+	decisions := PlanCleanup(images, policy, now)
+	require.Len(decisions, 6)
+	removed := []string{}
+	for _, d := range decisions {
+		if d.Remove {
+			removed = append(removed, d.Image.Name())
+		}
+	}
+	require.ElementsMatch([]string{"registry.io/project:v2", "registry.io/project:v3"}, removed)
+	// Image 2 is still tagged registry.io/project-tmp:v2, which is the last one of its repository
+	require.Equal(int64(300), ReclaimedSize(images, decisions))
+
+	policy.KeepLast = 0
+	decisions = PlanCleanup(images, policy, now)
+	require.Equal(int64(500), ReclaimedSize(images, decisions))
+}
+
+func TestGetProtectedImages(t *testing.T) {
+	require := require.New(t)
+
+	images, err := GetProtectedImages("")
+	require.NoError(err)
+	require.Empty(images)
+
+	ciuxConfig := filepath.Join(t.TempDir(), "ciux.sh")
+	content := `export CIUX_IMAGE_URL="registry.io/project:v1.0.0-untested"
+export CIUX_PROMOTED_IMAGE_URL="registry.io/project:v1.0.0"
+export CIUX_BUILD="true"
+export SPARK_PY_IMAGE="registry.io/spark-py:k8s-3.4.1"
+`
+	require.NoError(os.WriteFile(ciuxConfig, []byte(content), 0644))
+	images, err = GetProtectedImages(ciuxConfig)
+	require.NoError(err)
+	require.Equal([]string{"registry.io/project:v1.0.0", "registry.io/project:v1.0.0-untested", "registry.io/spark-py:k8s-3.4.1"}, images)
+
+	// Additional images of the 'images' section of the .ciux file
+	project := Project{
+		Config: ProjConfig{Images: []ImageConfig{{Name: "project-noscience", Dockerfile: "Dockerfile.noscience"}}},
+		Images: []Image{{Registry: "registry.io", Name: "project-noscience", Tag: "v1.0.0"}},
+	}
+	f, err := os.OpenFile(ciuxConfig, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(err)
+	require.NoError(bashWriter{}.Write(f, project.getImagesConfigVars()))
+	require.NoError(f.Close())
+	images, err = GetProtectedImages(ciuxConfig)
+	require.NoError(err)
+	require.Equal([]string{"registry.io/project-noscience:v1.0.0", "registry.io/project:v1.0.0", "registry.io/project:v1.0.0-untested", "registry.io/spark-py:k8s-3.4.1"}, images)
+}
+
+func TestFormatSize(t *testing.T) {
+	require := require.New(t)
+	require.Equal("999B", FormatSize(999))
+	require.Equal("1.5kB", FormatSize(1500))
+	require.Equal("2.3GB", FormatSize(2300000000))
+}
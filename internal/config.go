@@ -44,6 +44,14 @@ type DepConfig struct {
 	Pull    bool       `mapstructure:"pull" default:"false"`
 	Package string     `mapstructure:"package" default:""`
 	Labels  labels.Set `mapstructure:"labels"`
+	// Ordered list of candidate work branches for a git dependency, overrides the branch strategy of the project
+	BranchStrategy []string `mapstructure:"branchStrategy"`
+	// Tag or branch of a git dependency, e.g. v3.2.0
+	Ref string `mapstructure:"ref" default:""`
+	// Commit of a git dependency, can be abbreviated to at least 7 digits
+	Commit string `mapstructure:"commit" default:""`
+	// Semver range for the tag of a git dependency, e.g. ">=3.1.0 <4.0.0", the highest matching tag is used
+	Version string `mapstructure:"version" default:""`
 }
 
 type ProjConfig struct {
@@ -51,4 +59,52 @@ type ProjConfig struct {
 	Registry     string      `mapstructure:"registry" default:""`
 	Dependencies []DepConfig `mapstructure:"dependencies"`
 	SourcePathes []string    `mapstructure:"sourcePathes"`
+	// If true, images which are built by the CI are tagged with the untested suffix
+	// until they are promoted
+	UntestedTags bool `mapstructure:"untestedTags" default:"false"`
+	// Credentials for the registries
+	Registries []RegistryConfig `mapstructure:"registries"`
+	// Mirrors for the images of the dependencies
+	Mirrors Mirrors `mapstructure:"mirrors"`
+	// Platforms which must all exist for the project image to be in the registry, e.g. linux/amd64
+	Platforms []string `mapstructure:"platforms"`
+	// If true, the base images of the Dockerfiles inside the source pathes are image dependencies of the build
+	CheckBaseImages bool `mapstructure:"checkBaseImages" default:"false"`
+	// Strategy used to compute the tag of the project image, see ImageTagStrategies()
+	ImageTagStrategy string `mapstructure:"imageTagStrategy" default:""`
+	// Additional images built by the project
+	Images []ImageConfig `mapstructure:"images"`
+	// Dockerfile used to build the project image, relative to the project repository, default to Dockerfile
+	Dockerfile string `mapstructure:"dockerfile" default:""`
+	// If true, the files ignored by the .dockerignore file of the build context, i.e. the Dockerfile directory,
+	// are excluded from the source pathes
+	Dockerignore bool `mapstructure:"dockerignore" default:"false"`
+	// If set to "Dockerfile", the source pathes are the Dockerfile and the sources of its COPY and ADD instructions,
+	// sourcePathes entries are added to them, and the .dockerignore file is always applied
+	AutoSourcePathes string `mapstructure:"autoSourcePathes" default:""`
+	// Ordered list of candidate work branches for the git dependencies, see ResolveBranch,
+	// default to DefaultBranchStrategy
+	BranchStrategy []string `mapstructure:"branchStrategy"`
+}
+
+// GetDockerfile returns the Dockerfile used to build the project image
+func (c ProjConfig) GetDockerfile() string {
+	if c.Dockerfile == "" {
+		return "Dockerfile"
+	}
+	return c.Dockerfile
+}
+
+// ImageConfig describes an additional image built by the project, from its own source pathes
+type ImageConfig struct {
+	// Image name, e.g. fink-broker-noscience, ignored if Suffix is set
+	Name string `mapstructure:"name" default:""`
+	// Suffix added to the project name to get the image name, e.g. noscience
+	Suffix string `mapstructure:"suffix" default:""`
+	// Paths to track when building the image, default to the project sourcePathes
+	SourcePathes []string `mapstructure:"sourcePathes"`
+	// Registry which stores the image, default to the project registry
+	Registry string `mapstructure:"registry" default:""`
+	// Dockerfile used to build the image, relative to the project repository, default to the project Dockerfile
+	Dockerfile string `mapstructure:"dockerfile" default:""`
 }
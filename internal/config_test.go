@@ -31,4 +31,11 @@ func TestNewConfig(t *testing.T) {
 
 	require.Equal([]string{"rootfs", "homefs"}, c.SourcePathes)
 	require.Equal(expectedDep, c.Dependencies[0])
+
+	expectedRegistry := RegistryConfig{
+		Host:        "gitlab-registry.in2p3.fr",
+		Username:    "gitlab-ci-token",
+		PasswordEnv: "CI_JOB_TOKEN",
+	}
+	require.Equal([]RegistryConfig{expectedRegistry}, c.Registries)
 }
@@ -1,6 +1,11 @@
 package internal
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
 
 type Dependency struct {
 	Clone   bool
@@ -8,6 +13,20 @@ type Dependency struct {
 	Image   string
 	Pull    bool
 	Package string
+	// Module version of the installed Go package, resolved by InstallGoModules or recorded in the lock file
+	PackageVersion string
+	// Digest of the dependency image, set when the image existence is checked
+	ImageDigest string
+	// FROM instruction which uses the image, for the base images of the project Dockerfiles
+	BaseImage *BaseImage
+	// Reference of the base image once checked by CheckBaseImages, so that it is not checked twice
+	baseImageRef name.Reference
+	// Branch strategy of a git dependency, default to the one of the project
+	BranchStrategy []string
+	// Tag or branch, commit or semver range a git dependency is pinned to, see DepConfig
+	Ref     string
+	Commit  string
+	Version string
 }
 
 // String returns the string representation of the dependency
@@ -21,8 +40,17 @@ func (dep *Dependency) String() string {
 	}
 }
 
-// GetImageName returns the image name of the dependency
-func (dep *Dependency) GetImageName(imageRegistry string) (string, error) {
+// GetImageName returns the image name of the dependency, rewritten to its mirror if any
+func (dep *Dependency) GetImageName(imageRegistry string, mirrors Mirrors) (string, error) {
+	canonical, err := dep.GetCanonicalImageName(imageRegistry)
+	if err != nil {
+		return "", err
+	}
+	return mirrors.Rewrite(canonical)
+}
+
+// GetCanonicalImageName returns the image name of the dependency, as defined in the configuration
+func (dep *Dependency) GetCanonicalImageName(imageRegistry string) (string, error) {
 	if dep.Image != "" {
 		return dep.Image, nil
 	} else {
@@ -40,3 +68,91 @@ func (dep *Dependency) GetImageName(imageRegistry string) (string, error) {
 		return imageUrl, nil
 	}
 }
+
+// commitRegexp matches full and abbreviated commit hashes, abbreviated ones have at least 7 digits like in git
+var commitRegexp = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// validateRef checks that a git dependency is pinned to at most one of a ref, a commit or a version range
+func (c DepConfig) validateRef() error {
+	pins := 0
+	for _, pin := range []string{c.Ref, c.Commit, c.Version} {
+		if pin != "" {
+			pins++
+		}
+	}
+	if pins == 0 {
+		return nil
+	}
+	if pins > 1 {
+		return fmt.Errorf("only one of ref, commit or version can be set for dependency %s", c.Url)
+	}
+	if c.Url == "" {
+		return fmt.Errorf("ref, commit or version can only be set for git dependencies")
+	}
+	if len(c.BranchStrategy) != 0 {
+		return fmt.Errorf("branchStrategy can not be set with ref, commit or version for dependency %s", c.Url)
+	}
+	if c.Commit != "" && !commitRegexp.MatchString(c.Commit) {
+		return fmt.Errorf("invalid commit %s for dependency %s", c.Commit, c.Url)
+	}
+	if c.Version != "" {
+		if _, err := ParseSemVerRange(c.Version); err != nil {
+			return fmt.Errorf("invalid version for dependency %s: %v", c.Url, err)
+		}
+	}
+	return nil
+}
+
+// IsPinned returns true if a git dependency is pinned to a ref, a commit or a version range, instead of following a branch strategy
+func (dep *Dependency) IsPinned() bool {
+	return dep.Ref != "" || dep.Commit != "" || dep.Version != ""
+}
+
+// resolveRef resolves the ref, commit or version range of a pinned git dependency against its remote references,
+// LsRemote must have been called before
+func (dep *Dependency) resolveRef() error {
+	gitObj := dep.Git
+	if dep.Commit != "" {
+		hash, err := gitObj.ResolveRemoteCommit(dep.Commit)
+		if err != nil {
+			return err
+		}
+		if hash == "" && !dep.Clone {
+			return fmt.Errorf("abbreviated commit %s is not referenced by a branch or a tag of git repository %s, use the full hash for a dependency which is not cloned", dep.Commit, gitObj.Url)
+		}
+		gitObj.Ref = dep.Commit
+		gitObj.RemoteHash = hash
+		return nil
+	}
+	ref := dep.Ref
+	if dep.Version != "" {
+		versionRange, err := ParseSemVerRange(dep.Version)
+		if err != nil {
+			return err
+		}
+		ref = versionRange.MaxSatisfying(gitObj.RemoteTags)
+		if ref == "" {
+			return fmt.Errorf("no tag matches version %s for git repository %s", dep.Version, gitObj.Url)
+		}
+		gitObj.VersionRange = dep.Version
+	}
+	gitObj.Ref = ref
+	found, hash, err := gitObj.HasTag(ref)
+	if err != nil {
+		return fmt.Errorf("unable to check tag existence for git repository %s: %v", gitObj.Url, err)
+	}
+	if found {
+		gitObj.RemoteHash = hash
+		return nil
+	}
+	found, hash, err = gitObj.HasBranch(ref)
+	if err != nil {
+		return fmt.Errorf("unable to check branch existence for git repository %s: %v", gitObj.Url, err)
+	}
+	if found {
+		gitObj.WorkBranch = ref
+		gitObj.RemoteHash = hash
+		return nil
+	}
+	return fmt.Errorf("ref %s is neither a tag nor a branch of git repository %s", ref, gitObj.Url)
+}
@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveRef(t *testing.T) {
+	require := require.New(t)
+
+	gitDep, err := initGitRepo("ciux-dependency-ref-test-")
+	require.NoError(err)
+	depRoot, err := gitDep.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(depRoot)
+
+	commit1, _, err := gitDep.TaggedCommit("file1.txt", "commit1", "v3.1.0", true, author)
+	require.NoError(err)
+	commit2, _, err := gitDep.TaggedCommit("file2.txt", "commit2", "v3.2.0", false, author)
+	require.NoError(err)
+	require.NoError(gitDep.CreateBranch("develop"))
+	commit3, _, err := gitDep.TaggedCommit("file3.txt", "commit3", "v4.0.0", true, author)
+	require.NoError(err)
+	// Commit which is not referenced by a branch or a tag
+	worktree, err := gitDep.Repository.Worktree()
+	require.NoError(err)
+	untagged, err := worktree.Commit("untagged", &git.CommitOptions{Author: &author, AllowEmptyCommits: true})
+	require.NoError(err)
+	commit4, _, err := gitDep.TaggedCommit("file4.txt", "commit4", "nightly", false, author)
+	require.NoError(err)
+
+	tests := []struct {
+		dep                Dependency
+		expectedRef        string
+		expectedHash       string
+		expectedWorkBranch string
+	}{
+		{Dependency{Ref: "v3.1.0"}, "v3.1.0", commit1.String(), ""},
+		{Dependency{Ref: "v3.2.0"}, "v3.2.0", commit2.String(), ""},
+		{Dependency{Ref: "develop"}, "develop", commit4.String(), "develop"},
+		{Dependency{Ref: "nightly"}, "nightly", commit4.String(), ""},
+		{Dependency{Version: ">=3.1.0 <4.0.0"}, "v3.2.0", commit2.String(), ""},
+		{Dependency{Version: ">=3.0.0"}, "v4.0.0", commit3.String(), ""},
+		{Dependency{Commit: commit1.String()[:7]}, commit1.String()[:7], commit1.String(), ""},
+		{Dependency{Commit: commit4.String()}, commit4.String(), commit4.String(), ""},
+		// Peeled reference of an annotated tag
+		{Dependency{Commit: commit3.String()[:7]}, commit3.String()[:7], commit3.String(), ""},
+		// Full hash of a commit which is not referenced, it is not looked up
+		{Dependency{Commit: untagged.String()}, untagged.String(), untagged.String(), ""},
+	}
+	for _, tt := range tests {
+		tt.dep.Git = &Git{Url: "file://" + depRoot}
+		require.NoError(tt.dep.Git.LsRemote())
+		require.True(tt.dep.IsPinned())
+		require.NoError(tt.dep.resolveRef(), "dep=%+v", tt.dep)
+		require.Equal(tt.expectedRef, tt.dep.Git.Ref)
+		require.Equal(tt.expectedHash, tt.dep.Git.RemoteHash)
+		require.Equal(tt.expectedWorkBranch, tt.dep.Git.WorkBranch)
+	}
+
+	// Abbreviated commits which are not referenced can only be resolved in a clone
+	for _, dep := range []Dependency{{Ref: "v5.0.0"}, {Version: ">=5.0.0"}, {Commit: "deadbeef"}, {Commit: untagged.String()[:7]}} {
+		dep.Git = &Git{Url: "file://" + depRoot}
+		require.NoError(dep.Git.LsRemote())
+		require.Error(dep.resolveRef(), "dep=%+v", dep)
+	}
+
+	// Tags and commits are checked out after the clone
+	for _, dep := range []Dependency{{Ref: "v3.1.0"}, {Commit: commit1.String()[:7]}} {
+		dep.Git = &Git{Url: "file://" + depRoot}
+		require.NoError(dep.Git.LsRemote())
+		require.NoError(dep.resolveRef())
+		require.NoError(dep.Git.CloneOrOpen("", true))
+		cloneRoot, err := dep.Git.GetRoot()
+		require.NoError(err)
+		head, err := dep.Git.Repository.Head()
+		require.NoError(err)
+		require.Equal(commit1.String(), head.Hash().String())
+		require.NoError(os.RemoveAll(cloneRoot))
+	}
+
+	// Abbreviated commit which is not referenced, it is resolved in the clone
+	dep := Dependency{Commit: untagged.String()[:7], Clone: true, Git: &Git{Url: "file://" + depRoot}}
+	require.NoError(dep.Git.LsRemote())
+	require.NoError(dep.resolveRef())
+	require.Empty(dep.Git.RemoteHash)
+	require.NoError(dep.Git.CloneOrOpen("", true))
+	cloneRoot, err := dep.Git.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(cloneRoot)
+	head, err := dep.Git.Repository.Head()
+	require.NoError(err)
+	require.Equal(untagged.String(), head.Hash().String())
+	require.Equal(untagged.String(), dep.Git.RemoteHash)
+
+	// Missing commit in the clone
+	dep = Dependency{Commit: "deadbeef", Clone: true, Git: &Git{Url: "file://" + depRoot}}
+	require.NoError(dep.Git.LsRemote())
+	require.NoError(dep.resolveRef())
+	require.Error(dep.Git.CloneOrOpen(t.TempDir(), true))
+
+	// In-place repositories are checked out at the pinned ref if their worktree is clean
+	basePath := t.TempDir()
+	for _, ref := range []string{"v3.1.0", "v3.2.0"} {
+		dep := Dependency{Ref: ref, Git: &Git{Url: "file://" + depRoot}}
+		require.NoError(dep.Git.LsRemote())
+		require.NoError(dep.resolveRef())
+		require.NoError(dep.Git.CloneOrOpen(basePath, true))
+		head, err := dep.Git.Repository.Head()
+		require.NoError(err)
+		require.Equal(dep.Git.RemoteHash, head.Hash().String())
+	}
+	name, err := (&Git{Url: "file://" + depRoot}).GetName()
+	require.NoError(err)
+	require.NoError(os.WriteFile(filepath.Join(basePath, name, "file1.txt"), []byte("modified"), 0644))
+	dep = Dependency{Ref: "v3.1.0", Git: &Git{Url: "file://" + depRoot}}
+	require.NoError(dep.Git.LsRemote())
+	require.NoError(dep.resolveRef())
+	require.Error(dep.Git.CloneOrOpen(basePath, true))
+}
+
+func TestResolveRemoteCommitAmbiguous(t *testing.T) {
+	require := require.New(t)
+
+	gitObj := Git{Url: "https://example.com/repo", remoteRefs: []*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/main", plumbing.NewHash("abcdef1111111111111111111111111111111111")),
+		plumbing.NewHashReference("refs/tags/v1.0.0", plumbing.NewHash("abcdef1222222222222222222222222222222222")),
+	}}
+	_, err := gitObj.ResolveRemoteCommit("abcdef1")
+	require.Error(err)
+	require.Contains(err.Error(), "ambiguous")
+	hash, err := gitObj.ResolveRemoteCommit("abcdef12")
+	require.NoError(err)
+	require.Equal("abcdef1222222222222222222222222222222222", hash)
+
+	// Full hashes are not looked up
+	hash, err = gitObj.ResolveRemoteCommit("0123456789012345678901234567890123456789")
+	require.NoError(err)
+	require.Equal("0123456789012345678901234567890123456789", hash)
+}
+
+func TestValidateRef(t *testing.T) {
+	require := require.New(t)
+
+	url := "https://github.com/k8s-school/k8s-toolbox"
+	require.NoError(DepConfig{Url: url}.validateRef())
+	require.NoError(DepConfig{Url: url, Ref: "v3.2.0"}.validateRef())
+	require.NoError(DepConfig{Url: url, Commit: "abc1234"}.validateRef())
+	require.NoError(DepConfig{Url: url, Version: ">=3.1.0 <4.0.0"}.validateRef())
+
+	require.Error(DepConfig{Url: url, Ref: "v3.2.0", Commit: "abc1234"}.validateRef())
+	require.Error(DepConfig{Image: "alpine", Ref: "v3.2.0"}.validateRef())
+	require.Error(DepConfig{Url: url, Ref: "v3.2.0", BranchStrategy: []string{"main"}}.validateRef())
+	require.Error(DepConfig{Url: url, Commit: "main"}.validateRef())
+	require.Error(DepConfig{Url: url, Commit: "abc123"}.validateRef())
+	require.Error(DepConfig{Url: url, Version: ">=3.x"}.validateRef())
+}
@@ -0,0 +1,361 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// BaseImage is an image used by a FROM instruction of a Dockerfile
+type BaseImage struct {
+	Name string
+	// Value of the --platform flag, empty if it is not set or depends on a build argument
+	Platform string
+	// Name of the build stage, set with 'AS <name>'
+	Stage string
+	// Path of the Dockerfile, relative to the project repository
+	Dockerfile string
+	Line       int
+}
+
+func (b BaseImage) String() string {
+	return fmt.Sprintf("%s (%s:%d)", b.Name, b.Dockerfile, b.Line)
+}
+
+// dockerfileVarRegexp matches $VAR, ${VAR}, ${VAR:-default} and ${VAR:+alternative}
+var dockerfileVarRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:[-+][^}]*)?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// IsDockerfile returns true if a file name is the one of a Dockerfile,
+// i.e. Dockerfile, Containerfile, Dockerfile.<suffix> or <prefix>.Dockerfile
+func IsDockerfile(fileName string) bool {
+	return fileName == "Dockerfile" || fileName == "Containerfile" ||
+		strings.HasPrefix(fileName, "Dockerfile.") || strings.HasSuffix(fileName, ".Dockerfile")
+}
+
+// FindDockerfiles returns the Dockerfiles of a repository which belong to its source pathes, see SourceMatcher,
+// relative to the repository root, empty source pathes means the whole repository
+func FindDockerfiles(root string, sourcePathes []string) ([]string, error) {
+	matcher, err := NewSourceMatcher(sourcePathes)
+	if err != nil {
+		return nil, err
+	}
+	dockerfiles := []string{}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !IsDockerfile(d.Name()) {
+			return nil
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if matcher.Match(filepath.ToSlash(relPath)) {
+			dockerfiles = append(dockerfiles, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to search Dockerfiles in %s: %v", root, err)
+	}
+	sort.Strings(dockerfiles)
+	return dockerfiles, nil
+}
+
+// GetBaseImages returns the base images of the Dockerfiles inside the source pathes of a repository
+func GetBaseImages(root string, sourcePathes []string) ([]BaseImage, error) {
+	dockerfiles, err := FindDockerfiles(root, sourcePathes)
+	if err != nil {
+		return nil, err
+	}
+	baseImages := []BaseImage{}
+	for _, dockerfile := range dockerfiles {
+		f, err := os.Open(filepath.Join(root, dockerfile))
+		if err != nil {
+			return nil, fmt.Errorf("unable to open Dockerfile %s: %v", dockerfile, err)
+		}
+		images, err := ParseBaseImages(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse Dockerfile %s: %v", dockerfile, err)
+		}
+		for i := range images {
+			images[i].Dockerfile = dockerfile
+		}
+		baseImages = append(baseImages, images...)
+	}
+	return baseImages, nil
+}
+
+// ParseBaseImages returns the base images of the FROM instructions of a Dockerfile
+// ARG instructions before the first FROM are substituted with their default values,
+// 'scratch' and references to previous build stages are ignored
+func ParseBaseImages(r io.Reader) ([]BaseImage, error) {
+	instructions, err := readDockerfileInstructions(r)
+	if err != nil {
+		return nil, err
+	}
+	args := map[string]string{}
+	stages := map[string]bool{}
+	fromSeen := false
+	baseImages := []BaseImage{}
+	for _, instruction := range instructions {
+		fields := strings.Fields(instruction.text)
+		if len(fields) == 0 {
+			continue
+		}
+		keyword := strings.ToUpper(fields[0])
+		fields = fields[1:]
+		switch keyword {
+		case "ARG":
+			if fromSeen {
+				continue
+			}
+			setDockerfileArgs(fields, args)
+		case "FROM":
+			fromSeen = true
+			baseImage := BaseImage{Line: instruction.line}
+			for len(fields) != 0 && strings.HasPrefix(fields[0], "--") {
+				if platform, found := strings.CutPrefix(fields[0], "--platform="); found {
+					baseImage.Platform = substituteDockerfileArgs(platform, args)
+					if strings.Contains(baseImage.Platform, "$") {
+						// Automatic platform arguments, like $BUILDPLATFORM, are set by the builder
+						baseImage.Platform = ""
+					}
+				}
+				fields = fields[1:]
+			}
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("line %d: FROM instruction without image", instruction.line)
+			}
+			if len(fields) >= 3 && strings.EqualFold(fields[1], "AS") {
+				baseImage.Stage = fields[2]
+			}
+			imageName := fields[0]
+			baseImage.Name = substituteDockerfileArgs(imageName, args)
+			if baseImage.Name == "" || strings.Contains(baseImage.Name, "$") {
+				return nil, fmt.Errorf("line %d: unable to resolve base image %s, a build argument has no default value", instruction.line, imageName)
+			}
+			isStage := stages[strings.ToLower(baseImage.Name)]
+			if baseImage.Stage != "" {
+				stages[strings.ToLower(baseImage.Stage)] = true
+			}
+			if isStage || baseImage.Name == "scratch" {
+				continue
+			}
+			baseImages = append(baseImages, baseImage)
+		}
+	}
+	return baseImages, nil
+}
+
+// DockerfileSource is a source of a COPY or ADD instruction of a Dockerfile
+type DockerfileSource struct {
+	// Path of the source, relative to the build context, or to the project repository for GetDockerfileSources
+	Path string
+	// COPY or ADD
+	Instruction string
+	// Path of the Dockerfile, relative to the project repository
+	Dockerfile string
+	Line       int
+}
+
+func (s DockerfileSource) String() string {
+	return fmt.Sprintf("%s %s (%s:%d)", s.Instruction, s.Path, s.Dockerfile, s.Line)
+}
+
+// IsPattern returns true if the source contains wildcards
+func (s DockerfileSource) IsPattern() bool {
+	return strings.ContainsAny(s.Path, "*?[")
+}
+
+// ParseDockerfileSources returns the sources of the COPY and ADD instructions of a Dockerfile, relative to the build context
+// build arguments are substituted with their default values, and the instructions which copy files from
+// a build stage or an image (--from), from a URL or from a here-document are ignored
+func ParseDockerfileSources(r io.Reader) ([]DockerfileSource, error) {
+	instructions, err := readDockerfileInstructions(r)
+	if err != nil {
+		return nil, err
+	}
+	args := map[string]string{}
+	sources := []DockerfileSource{}
+	for _, instruction := range instructions {
+		keyword, rest, _ := strings.Cut(instruction.text, " ")
+		keyword = strings.ToUpper(keyword)
+		switch keyword {
+		case "ARG":
+			setDockerfileArgs(strings.Fields(rest), args)
+			continue
+		case "COPY", "ADD":
+		default:
+			continue
+		}
+		fromStage := false
+		rest = strings.TrimSpace(rest)
+		for strings.HasPrefix(rest, "--") {
+			var flag string
+			flag, rest, _ = strings.Cut(rest, " ")
+			rest = strings.TrimSpace(rest)
+			if strings.HasPrefix(flag, "--from=") {
+				fromStage = true
+			}
+		}
+		if fromStage {
+			continue
+		}
+		var fields []string
+		if strings.HasPrefix(rest, "[") {
+			if err := json.Unmarshal([]byte(rest), &fields); err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s instruction: %v", instruction.line, keyword, err)
+			}
+		} else {
+			fields = strings.Fields(rest)
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: %s instruction without source or destination", instruction.line, keyword)
+		}
+		for _, field := range fields[:len(fields)-1] {
+			if strings.HasPrefix(field, "<<") || strings.Contains(field, "://") || strings.HasPrefix(field, "git@") {
+				continue
+			}
+			source := substituteDockerfileArgs(field, args)
+			if source == "" || strings.Contains(source, "$") {
+				return nil, fmt.Errorf("line %d: unable to resolve %s source %s, a build argument has no default value", instruction.line, keyword, field)
+			}
+			sources = append(sources, DockerfileSource{Path: source, Instruction: keyword, Line: instruction.line})
+		}
+	}
+	return sources, nil
+}
+
+// GetDockerfileSources returns the sources of the COPY and ADD instructions of a Dockerfile, relative to the repository root,
+// the build context is the directory of the Dockerfile
+func GetDockerfileSources(root string, dockerfile string) ([]DockerfileSource, error) {
+	f, err := os.Open(filepath.Join(root, dockerfile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open Dockerfile %s: %v", dockerfile, err)
+	}
+	defer f.Close()
+	sources, err := ParseDockerfileSources(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse Dockerfile %s: %v", dockerfile, err)
+	}
+	contextDir := path.Dir(filepath.ToSlash(dockerfile))
+	for i := range sources {
+		sourcePath := path.Join(contextDir, strings.TrimPrefix(sources[i].Path, "/"))
+		if sourcePath == ".." || strings.HasPrefix(sourcePath, "../") {
+			return nil, fmt.Errorf("%s source %s is outside of the build context (%s:%d)", sources[i].Instruction, sources[i].Path, dockerfile, sources[i].Line)
+		}
+		sources[i].Path = sourcePath
+		sources[i].Dockerfile = dockerfile
+	}
+	return sources, nil
+}
+
+// DockerfileSourcePathes returns the sourcePathes entries which track a Dockerfile and the sources of its COPY and ADD instructions,
+// wildcard sources are anchored patterns
+func DockerfileSourcePathes(root string, dockerfile string) ([]string, error) {
+	sources, err := GetDockerfileSources(root, dockerfile)
+	if err != nil {
+		return nil, err
+	}
+	sourcePathes := []string{path.Clean(filepath.ToSlash(dockerfile))}
+	for _, source := range sources {
+		sourcePath := source.Path
+		if source.IsPattern() {
+			sourcePath = "/" + sourcePath
+		}
+		if !slices.Contains(sourcePathes, sourcePath) {
+			sourcePathes = append(sourcePathes, sourcePath)
+		}
+	}
+	return sourcePathes, nil
+}
+
+// dockerfileInstruction is an instruction of a Dockerfile, with its line continuations joined
+type dockerfileInstruction struct {
+	text string
+	// Line of the start of the instruction
+	line int
+}
+
+func readDockerfileInstructions(r io.Reader) ([]dockerfileInstruction, error) {
+	instructions := []dockerfileInstruction{}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	var current *dockerfileInstruction
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") || (line == "" && current == nil) {
+			continue
+		}
+		continued := strings.HasSuffix(line, "\\")
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\\"))
+		if current == nil {
+			current = &dockerfileInstruction{text: line, line: lineNumber}
+		} else if line != "" {
+			current.text += " " + line
+		}
+		if !continued {
+			instructions = append(instructions, *current)
+			current = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if current != nil {
+		instructions = append(instructions, *current)
+	}
+	return instructions, nil
+}
+
+// setDockerfileArgs records the default values of the fields of an ARG instruction
+func setDockerfileArgs(fields []string, args map[string]string) {
+	for _, field := range fields {
+		if argName, value, found := strings.Cut(field, "="); found {
+			args[argName] = substituteDockerfileArgs(strings.Trim(value, `"'`), args)
+		}
+	}
+}
+
+// substituteDockerfileArgs replaces the build arguments in a string, unknown arguments are kept as is
+func substituteDockerfileArgs(s string, args map[string]string) string {
+	return dockerfileVarRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		groups := dockerfileVarRegexp.FindStringSubmatch(match)
+		argName := groups[1]
+		if argName == "" {
+			argName = groups[3]
+		}
+		value, isSet := args[argName]
+		switch {
+		case strings.HasPrefix(groups[2], ":-") && value == "":
+			return groups[2][2:]
+		case strings.HasPrefix(groups[2], ":+"):
+			if value != "" {
+				return groups[2][2:]
+			}
+			return ""
+		case !isSet:
+			return match
+		}
+		return value
+	})
+}
@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBaseImages(t *testing.T) {
+	require := require.New(t)
+
+	dockerfile := `# syntax=docker/dockerfile:1
+ARG PYTHON_VERSION=3.11
+ARG REGISTRY="gitlab-registry.in2p3.fr/astrolabsoftware/fink"
+ARG SPARK_IMAGE=${REGISTRY}/spark-py:k8s-3.4.1
+
+FROM --platform=$BUILDPLATFORM golang:1.21 AS builder
+RUN go build \
+    -o /app \
+    ./...
+
+from python:${PYTHON_VERSION}-slim as runtime
+COPY --from=builder /app /app
+
+FROM ${SPARK_IMAGE}
+ARG PYTHON_VERSION=3.12
+FROM --platform=linux/arm64 \
+    alpine:${ALPINE_VERSION:-3.19}
+
+FROM builder
+FROM scratch
+`
+	images, err := ParseBaseImages(strings.NewReader(dockerfile))
+	require.NoError(err)
+	require.Equal([]BaseImage{
+		{Name: "golang:1.21", Stage: "builder", Line: 6},
+		{Name: "python:3.11-slim", Stage: "runtime", Line: 11},
+		{Name: "gitlab-registry.in2p3.fr/astrolabsoftware/fink/spark-py:k8s-3.4.1", Line: 14},
+		{Name: "alpine:3.19", Platform: "linux/arm64", Line: 16},
+	}, images)
+
+	_, err = ParseBaseImages(strings.NewReader("ARG BASE\nFROM ${BASE}\n"))
+	require.Error(err)
+	_, err = ParseBaseImages(strings.NewReader("FROM --platform=linux/amd64\n"))
+	require.Error(err)
+}
+
+func TestCheckBaseImages(t *testing.T) {
+	require := require.New(t)
+
+	gitObj, err := initGitRepo("ciux-baseimages-test-")
+	require.NoError(err)
+	root, err := gitObj.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	url := startTestRegistry(t)
+	pushRandomImage(t, url+"/base:v1")
+	pushMultiPlatformImage(t, url+"/multi:v1", "linux/amd64")
+
+	require.NoError(os.MkdirAll(filepath.Join(root, "build", "app"), 0755))
+	require.NoError(os.WriteFile(filepath.Join(root, "build", "app", "Dockerfile"), []byte("FROM "+url+"/base:v1\n"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(root, "build", "tools.Dockerfile"), []byte("FROM "+url+"/base:v1\n"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(root, "README.md"), []byte("FROM nothing\n"), 0644))
+
+	dockerfiles, err := FindDockerfiles(root, []string{"build", "README.md", "missing"})
+	require.NoError(err)
+	require.Equal([]string{filepath.Join("build", "app", "Dockerfile"), filepath.Join("build", "tools.Dockerfile")}, dockerfiles)
+
+	// Empty and pattern source pathes
+	require.NoError(os.WriteFile(filepath.Join(root, "Dockerfile"), []byte("FROM scratch\n"), 0644))
+	findTests := []struct {
+		sourcePathes []string
+		expected     []string
+	}{
+		{nil, []string{"Dockerfile", filepath.Join("build", "app", "Dockerfile"), filepath.Join("build", "tools.Dockerfile")}},
+		{[]string{"**/Dockerfile"}, []string{"Dockerfile", filepath.Join("build", "app", "Dockerfile")}},
+		{[]string{"build", "!**/app/"}, []string{filepath.Join("build", "tools.Dockerfile")}},
+		{[]string{"build", "dockerignore:/build/app"}, []string{filepath.Join("build", "tools.Dockerfile")}},
+		{[]string{"*.py"}, []string{}},
+	}
+	for _, tt := range findTests {
+		dockerfiles, err = FindDockerfiles(root, tt.sourcePathes)
+		require.NoError(err)
+		require.Equal(tt.expected, dockerfiles, "sourcePathes=%v", tt.sourcePathes)
+	}
+
+	project := Project{GitMain: &gitObj, SourcePathes: []string{"build"}}
+	require.NoError(project.addBaseImages())
+	require.Len(project.Dependencies, 1)
+	refs, err := project.CheckBaseImages()
+	require.NoError(err)
+	require.Len(refs, 1)
+	require.NotEmpty(project.Dependencies[0].ImageDigest)
+
+	// Base images checked by CheckBaseImages are not checked again
+	baseRef, err := name.ParseReference(url + "/base:v1")
+	require.NoError(err)
+	require.NoError(remote.Delete(baseRef))
+	refs, err = project.CheckDepImages()
+	require.NoError(err)
+	require.Len(refs, 1)
+	pushRandomImage(t, url+"/base:v1")
+
+	vars, err := project.getImageDepConfigVars(project.Dependencies[0])
+	require.NoError(err)
+	require.Equal("BASE_IMAGE", vars[0].Name)
+	require.Equal(url+"/base:v1", vars[0].Value)
+	require.Equal("Base image of "+filepath.Join("build", "app", "Dockerfile"), vars[0].Comment)
+
+	// Missing image
+	require.NoError(os.WriteFile(filepath.Join(root, "build", "Dockerfile.missing"), []byte("FROM "+url+"/missing:v1\n"), 0644))
+	project = Project{GitMain: &gitObj, SourcePathes: []string{"build"}}
+	require.NoError(project.addBaseImages())
+	_, err = project.CheckBaseImages()
+	require.ErrorIs(err, ErrImageNotFound)
+	require.ErrorContains(err, "Dockerfile.missing:1")
+	require.NoError(os.Remove(filepath.Join(root, "build", "Dockerfile.missing")))
+
+	// Missing platform
+	require.NoError(os.WriteFile(filepath.Join(root, "build", "Dockerfile.arm"), []byte("FROM --platform=linux/arm64 "+url+"/multi:v1\n"), 0644))
+	project = Project{GitMain: &gitObj, SourcePathes: []string{"build"}}
+	require.NoError(project.addBaseImages())
+	_, err = project.CheckBaseImages()
+	require.ErrorContains(err, "linux/arm64")
+}
+
+func TestParseDockerfileSources(t *testing.T) {
+	require := require.New(t)
+
+	dockerfile := `ARG SRC_DIR=fink_broker
+FROM python:3.11 AS builder
+COPY requirements.txt /tmp/
+COPY --chown=fink:fink ${SRC_DIR} \
+    bin /home/fink/
+ADD ["deps/install.sh", "/deps/"]
+ADD https://example.com/archive.tar.gz /tmp/
+COPY <<EOF /etc/config
+EOF
+FROM python:3.11-slim
+COPY --from=builder /home/fink /home/fink
+COPY conf/*.yaml /conf/
+`
+	sources, err := ParseDockerfileSources(strings.NewReader(dockerfile))
+	require.NoError(err)
+	require.Equal([]DockerfileSource{
+		{Path: "requirements.txt", Instruction: "COPY", Line: 3},
+		{Path: "fink_broker", Instruction: "COPY", Line: 4},
+		{Path: "bin", Instruction: "COPY", Line: 4},
+		{Path: "deps/install.sh", Instruction: "ADD", Line: 6},
+		{Path: "conf/*.yaml", Instruction: "COPY", Line: 12},
+	}, sources)
+
+	_, err = ParseDockerfileSources(strings.NewReader("ARG SRC\nCOPY ${SRC} /src\n"))
+	require.Error(err)
+	_, err = ParseDockerfileSources(strings.NewReader("COPY /src\n"))
+	require.Error(err)
+}
+
+func TestDockerfileSourcePathes(t *testing.T) {
+	require := require.New(t)
+
+	root := t.TempDir()
+	require.NoError(os.MkdirAll(filepath.Join(root, "tools"), 0755))
+	require.NoError(os.WriteFile(filepath.Join(root, "tools", "Dockerfile"), []byte("FROM alpine\nCOPY . /src\nCOPY *.sh bin/ /\n"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(root, "tools", ".dockerignore"), []byte("*.md\n"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(root, "Dockerfile"), []byte("FROM alpine\nCOPY ../outside /src\n"), 0644))
+
+	sourcePathes, err := DockerfileSourcePathes(root, filepath.Join("tools", "Dockerfile"))
+	require.NoError(err)
+	require.Equal([]string{"tools/Dockerfile", "tools", "/tools/*.sh", "tools/bin"}, sourcePathes)
+
+	config := ProjConfig{AutoSourcePathes: AutoSourcePathesDockerfile}
+	sourcePathes, err = config.resolveSourcePathes(root, []string{"!tools/tests/"}, filepath.Join("tools", "Dockerfile"))
+	require.NoError(err)
+	require.Equal([]string{"tools/Dockerfile", "tools", "/tools/*.sh", "tools/bin", "!tools/tests/", "dockerignore:/tools/*.md"}, sourcePathes)
+
+	_, err = DockerfileSourcePathes(root, "Dockerfile")
+	require.ErrorContains(err, "outside of the build context")
+	_, err = DockerfileSourcePathes(root, "missing/Dockerfile")
+	require.Error(err)
+}
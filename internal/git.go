@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
@@ -27,6 +28,14 @@ type Git struct {
 	// Hash for the HEAD of the remote work branch
 	RemoteHash string
 	WorkBranch string
+	// Branch of the HEAD advertised by the remote repository, i.e. its default branch
+	RemoteHead string
+	// Tag, branch or commit the repository is pinned to, empty if it follows a branch strategy
+	Ref string
+	// Semver range the Ref tag has been resolved from
+	VersionRange string
+	// References of the remote repository, listed by LsRemote
+	remoteRefs []*plumbing.Reference
 }
 
 // GitSemverTagMap ...
@@ -73,11 +82,16 @@ func FormatTags(tags *map[plumbing.Hash]*plumbing.Reference) map[string]string {
 	return tagMap
 }
 
+// MainBranch returns the default branch of the repository and the hash of its head,
+// i.e. the branch of the HEAD advertised by the remote if LsRemote has been called, or main/master
 func (gitObj *Git) MainBranch() (string, string, error) {
 
 	mainBranch := ""
 	found := false
 	mainNames := []string{"main", "master"}
+	if gitObj.RemoteHead != "" {
+		mainNames = append([]string{gitObj.RemoteHead}, mainNames...)
+	}
 	var hash string
 
 	name, err := gitObj.GetName()
@@ -181,6 +195,11 @@ func (gitObj *Git) CloneOrOpen(destBasePath string, singleBranch bool) error {
 			return err
 		}
 	}
+	// A repository pinned to a tag or a commit is fully cloned, then the commit is checked out
+	pinned := gitObj.Ref != "" && gitObj.Ref != gitObj.WorkBranch
+	if pinned {
+		singleBranch = false
+	}
 	var refName plumbing.ReferenceName
 	if singleBranch {
 		refName = plumbing.ReferenceName(gitObj.WorkBranch)
@@ -208,6 +227,94 @@ func (gitObj *Git) CloneOrOpen(destBasePath string, singleBranch bool) error {
 		return fmt.Errorf("unable to clone git repository %s: %v", gitObj.Url, err)
 	}
 	gitObj.Repository = repository
+	if pinned {
+		err = gitObj.checkoutPinnedRef()
+		if err != nil {
+			return fmt.Errorf("unable to checkout ref %s: %v", gitObj.Ref, err)
+		}
+	}
+	return nil
+}
+
+// checkoutPinnedRef checks out the commit of the pinned ref,
+// an in-place repository which is not at this commit must have a clean worktree
+func (gitObj *Git) checkoutPinnedRef() error {
+	if gitObj.RemoteHash == "" {
+		// Abbreviated commit which is not referenced on the remote, see ResolveRemoteCommit
+		hash, err := gitObj.resolveCommit(gitObj.Ref)
+		if err != nil {
+			return err
+		}
+		gitObj.RemoteHash = hash
+	}
+	if gitObj.InPlace {
+		head, err := gitObj.Repository.Head()
+		if err != nil {
+			return fmt.Errorf("unable to get HEAD of git repository %s: %v", gitObj.Url, err)
+		}
+		if head.Hash().String() == gitObj.RemoteHash {
+			return nil
+		}
+		dirtyFiles, err := gitObj.GetDirtySourceFiles(nil)
+		if err != nil {
+			return err
+		}
+		if len(dirtyFiles) != 0 {
+			return fmt.Errorf("in-place git repository %s is at commit %s and has modified files %v, it can not be checked out at commit %s", gitObj.Url, head.Hash(), dirtyFiles, gitObj.RemoteHash)
+		}
+		slog.Info("Checkout pinned ref in in-place repository", "url", gitObj.Url, "ref", gitObj.Ref, "hash", gitObj.RemoteHash)
+	}
+	return gitObj.CheckoutHash(gitObj.RemoteHash)
+}
+
+// CheckoutHash checks out a commit in detached HEAD mode, the hash can be abbreviated
+// the commit is fetched from origin if it is not available locally
+func (gitObj *Git) CheckoutHash(hash string) error {
+	resolve := func() (plumbing.Hash, error) {
+		commitHash := plumbing.NewHash(hash)
+		if len(hash) != len(commitHash.String()) {
+			resolved, err := gitObj.Repository.ResolveRevision(plumbing.Revision(hash))
+			if err != nil {
+				return plumbing.ZeroHash, plumbing.ErrObjectNotFound
+			}
+			commitHash = *resolved
+		}
+		_, err := gitObj.Repository.CommitObject(commitHash)
+		return commitHash, err
+	}
+	commitHash, err := resolve()
+	if err == plumbing.ErrObjectNotFound {
+		slog.Debug("Fetch commit from origin", "url", gitObj.Url, "hash", hash)
+		err = gitObj.fetchOrigin()
+		if err != nil {
+			return err
+		}
+		commitHash, err = resolve()
+	}
+	if err != nil {
+		return fmt.Errorf("commit %s is not reachable in git repository %s: %v", hash, gitObj.Url, err)
+	}
+	worktree, err := gitObj.Repository.Worktree()
+	if err != nil {
+		return fmt.Errorf("unable to get worktree: %v", err)
+	}
+	err = worktree.Checkout(&git.CheckoutOptions{Hash: commitHash})
+	if err != nil {
+		return fmt.Errorf("unable to checkout commit %s: %v", hash, err)
+	}
+	return nil
+}
+
+// fetchOrigin fetches the branches and tags of origin
+func (gitObj *Git) fetchOrigin() error {
+	err := gitObj.Repository.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+		Tags:       git.AllTags,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("unable to fetch git repository %s: %v", gitObj.Url, err)
+	}
 	return nil
 }
 
@@ -237,7 +344,11 @@ func (gitObj *Git) LsRemote() error {
 	// 2023/10/13 18:18:06 Tags found: v0.1^{}
 	// 2023/10/13 18:18:06 Tags found: v0.5^{}
 
+	gitObj.remoteRefs = refs
 	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference {
+			gitObj.RemoteHead = ref.Target().Short()
+		}
 		if ref.Name().IsBranch() {
 			gitObj.RemoteBranches = append(gitObj.RemoteBranches, ref.Name().Short())
 		}
@@ -305,6 +416,164 @@ func (gitObj *Git) HasBranch(branchname string) (bool, string, error) {
 	return found, hash.String(), nil
 }
 
+// HasTag returns true if the tag exists in the remote repository
+// and the hash of the commit it points to
+// LsRemote must have been called before
+func (gitObj *Git) HasTag(tagname string) (bool, string, error) {
+	if gitObj.remoteRefs == nil {
+		return false, "", fmt.Errorf("references of git repository %s have not been listed", gitObj.Url)
+	}
+	found := false
+	hash := plumbing.ZeroHash
+	tagRef := plumbing.NewTagReferenceName(tagname)
+	for _, ref := range gitObj.remoteRefs {
+		if ref.Name() == tagRef && !found {
+			found = true
+			hash = ref.Hash()
+		} else if ref.Name().String() == tagRef.String()+"^{}" {
+			// The peeled reference of an annotated tag is the commit
+			found = true
+			hash = ref.Hash()
+			break
+		}
+	}
+	slog.Debug("Tag search", "url", gitObj.Url, "tagname", tagname, "found", found)
+	return found, hash.String(), nil
+}
+
+// HasRemoteCommit returns true if a commit, given by its full hash, exists in the remote repository
+// the commit is looked up in the references listed by LsRemote, then in a fetch of the repository,
+// so that a commit which is no longer the head of a branch is found
+// LsRemote must have been called before
+func (gitObj *Git) HasRemoteCommit(hash string) (bool, error) {
+	if gitObj.remoteRefs == nil {
+		return false, fmt.Errorf("references of git repository %s have not been listed", gitObj.Url)
+	}
+	for _, ref := range gitObj.remoteRefs {
+		if ref.Type() == plumbing.HashReference && ref.Hash().String() == hash {
+			return true, nil
+		}
+	}
+	slog.Debug("Fetch git repository to look up commit", "url", gitObj.Url, "commit", hash)
+	repository, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
+		URL:  gitObj.Url,
+		Tags: git.AllTags,
+	})
+	if err != nil {
+		return false, fmt.Errorf("unable to fetch git repository %s: %v", gitObj.Url, err)
+	}
+	_, err = repository.CommitObject(plumbing.NewHash(hash))
+	if err == plumbing.ErrObjectNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("unable to look up commit %s in git repository %s: %v", hash, gitObj.Url, err)
+	}
+	return true, nil
+}
+
+// ResolveRemoteCommit returns the full hash of a commit of the remote repository, the commit can be abbreviated
+// a full hash is returned as is, an abbreviated one is looked up in the references listed by LsRemote,
+// if no reference matches, an empty hash is returned and the commit is resolved in the clone, see checkoutPinnedRef
+// LsRemote must have been called before
+func (gitObj *Git) ResolveRemoteCommit(commit string) (string, error) {
+	if len(commit) == len(plumbing.ZeroHash.String()) {
+		return commit, nil
+	}
+	if gitObj.remoteRefs == nil {
+		return "", fmt.Errorf("references of git repository %s have not been listed", gitObj.Url)
+	}
+	matches := map[plumbing.Hash]bool{}
+	for _, ref := range gitObj.remoteRefs {
+		if ref.Type() == plumbing.HashReference && strings.HasPrefix(ref.Hash().String(), commit) {
+			matches[ref.Hash()] = true
+		}
+	}
+	if len(matches) > 1 {
+		return "", fmt.Errorf("abbreviated commit %s is ambiguous in git repository %s", commit, gitObj.Url)
+	}
+	for hash := range matches {
+		return hash.String(), nil
+	}
+	slog.Debug("Abbreviated commit not referenced, resolve it in the clone", "url", gitObj.Url, "commit", commit)
+	return "", nil
+}
+
+// resolveCommit returns the full hash of a commit of the local repository, the commit can be abbreviated
+// the repository is fetched from origin if the commit is not available locally,
+// an abbreviated hash which matches several commits is an error
+func (gitObj *Git) resolveCommit(commit string) (string, error) {
+	lookup := func() ([]plumbing.Hash, error) {
+		matches := []plumbing.Hash{}
+		iter, err := gitObj.Repository.CommitObjects()
+		if err != nil {
+			return nil, fmt.Errorf("unable to list commits of git repository %s: %v", gitObj.Url, err)
+		}
+		err = iter.ForEach(func(c *object.Commit) error {
+			if strings.HasPrefix(c.Hash.String(), commit) {
+				matches = append(matches, c.Hash)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list commits of git repository %s: %v", gitObj.Url, err)
+		}
+		return matches, nil
+	}
+	matches, err := lookup()
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		err = gitObj.fetchOrigin()
+		if err != nil {
+			return "", err
+		}
+		matches, err = lookup()
+		if err != nil {
+			return "", err
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("commit %s not found in git repository %s", commit, gitObj.Url)
+	case 1:
+		return matches[0].String(), nil
+	default:
+		return "", fmt.Errorf("abbreviated commit %s is ambiguous in git repository %s", commit, gitObj.Url)
+	}
+}
+
+// GetDirtySourceFiles returns the tracked files of the worktree which are modified, staged or deleted and belong to the source pathes,
+// empty source pathes means the whole repository, and untracked files are ignored, like in IsDirty
+func (g *Git) GetDirtySourceFiles(sourcePathes []string) ([]string, error) {
+	w, err := g.Repository.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("unable to find worktree: %v", err)
+	}
+	status, err := w.Status()
+	if err != nil {
+		return nil, fmt.Errorf("unable to find worktree status: %v", err)
+	}
+	matcher, err := NewSourceMatcher(sourcePathes)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for file, fileStatus := range status {
+		if fileStatus.Worktree == git.Untracked {
+			continue
+		}
+		if fileStatus.Worktree == git.Unmodified && fileStatus.Staging == git.Unmodified {
+			continue
+		}
+		if matcher.Match(filepath.ToSlash(file)) {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
 // IsDirty returns true if all the files are in Unmodified or Untracked status.
 func IsDirty(s git.Status) bool {
 	for _, status := range s {
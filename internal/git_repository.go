@@ -1,56 +1,167 @@
 package internal
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
 	"slices"
+	"sort"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
-func HasDiff(current *object.Commit, ancestor *object.Commit, root string, pathes []string) (bool, error) {
-	patch, err := ancestor.Patch(current)
+// sourceDiffer compares the source pathes of commits without computing patches, i.e. without reading file contents:
+// plain source pathes are compared with a single lookup of their git object in the tree of each commit,
+// and patterns with a diff of the trees, which skips the identical subtrees
+type sourceDiffer struct {
+	// Clean plain source pathes, "." for the whole repository
+	pathes []string
+	// Matcher of the source pathes if they contain patterns, nil otherwise
+	matcher *SourceMatcher
+}
+
+// newSourceDiffer returns the differ for source pathes, plain source pathes must exist in the repository root,
+// empty source pathes means the whole repository
+func newSourceDiffer(root string, pathes []string) (*sourceDiffer, error) {
+	if len(pathes) == 0 {
+		return &sourceDiffer{pathes: []string{"."}}, nil
+	}
+	matcher, err := NewSourceMatcher(pathes)
 	if err != nil {
-		return false, fmt.Errorf("unable to get patch: %v", err)
-	}
-	// Get the file patch
-	for _, fp := range patch.FilePatches() {
-		from, to := fp.Files()
-		slog.Debug("File patch : ", "from", from, "to", to)
-		if from != nil {
-			slog.Info("File patch", "from", from.Path())
-			codeChange, err := IsFileInSourcePathes(root, from.Path(), pathes)
-			if err != nil {
-				return false, err
-			}
-			if codeChange {
-				if to != nil {
-					slog.Debug("Source file changed", "path", to.Path())
-				} else {
-					slog.Debug("Source file removed", "path", from.Path())
-				}
-				return true, nil
-			}
-		} else if to != nil {
-			slog.Info("File patch", "to", to.Path())
-			codeChange, err := IsFileInSourcePathes(root, to.Path(), pathes)
-			if err != nil {
-				return false, err
-			}
-			if codeChange {
-				slog.Debug("Source file changed", "path", to.Path())
+		return nil, err
+	}
+	d := sourceDiffer{}
+	for _, p := range pathes {
+		if isSourcePattern(p) {
+			continue
+		}
+		if filepath.IsAbs(p) {
+			return nil, fmt.Errorf("invalid argument sourcePath must be relative: sourcePath=%q", p)
+		}
+		// Plain source pathes must exist
+		if _, err := os.Stat(filepath.Join(root, p)); err != nil {
+			return nil, err
+		}
+		d.pathes = append(d.pathes, path.Clean(p))
+	}
+	if !matcher.IsPlain() {
+		d.matcher = matcher
+		return &d, nil
+	}
+	sort.Strings(d.pathes)
+	d.pathes = slices.Compact(d.pathes)
+	return &d, nil
+}
+
+// lookupSourcePath returns the git object hash and mode of a source path in a tree, or "missing" if it does not exist
+func lookupSourcePath(tree *object.Tree, p string) (string, filemode.FileMode, error) {
+	if p == "." {
+		return tree.Hash.String(), filemode.Dir, nil
+	}
+	entry, err := tree.FindEntry(p)
+	if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+		return "missing", filemode.Empty, nil
+	} else if err != nil {
+		return "", filemode.Empty, fmt.Errorf("unable to find source path %s: %v", p, err)
+	}
+	return entry.Hash.String(), entry.Mode, nil
+}
+
+// hasDiff returns true if the source pathes differ between two commits
+func (d *sourceDiffer) hasDiff(current *object.Commit, ancestor *object.Commit) (bool, error) {
+	if current.TreeHash == ancestor.TreeHash {
+		return false, nil
+	}
+	currentTree, err := current.Tree()
+	if err != nil {
+		return false, fmt.Errorf("unable to retrieve tree of commit %s: %v", current.Hash, err)
+	}
+	ancestorTree, err := ancestor.Tree()
+	if err != nil {
+		return false, fmt.Errorf("unable to retrieve tree of commit %s: %v", ancestor.Hash, err)
+	}
+
+	if d.matcher != nil {
+		changes, err := object.DiffTree(ancestorTree, currentTree)
+		if err != nil {
+			return false, fmt.Errorf("unable to compare commits %s and %s: %v", ancestor.Hash, current.Hash, err)
+		}
+		for _, change := range changes {
+			if d.matcher.Match(change.From.Name) || d.matcher.Match(change.To.Name) {
+				slog.Debug("Source file changed", "from", change.From.Name, "to", change.To.Name)
 				return true, nil
 			}
 		}
+		return false, nil
+	}
+
+	for _, p := range d.pathes {
+		currentHash, currentMode, err := lookupSourcePath(currentTree, p)
+		if err != nil {
+			return false, err
+		}
+		ancestorHash, ancestorMode, err := lookupSourcePath(ancestorTree, p)
+		if err != nil {
+			return false, err
+		}
+		// A mode change, e.g. a file made executable, is a change of the sources
+		if currentHash != ancestorHash || currentMode != ancestorMode {
+			slog.Debug("Source path changed", "path", p)
+			return true, nil
+		}
 	}
 	return false, nil
 }
 
+// isCodeChange returns true if the source pathes of a commit differ from the ones of any of its parents
+func (d *sourceDiffer) isCodeChange(commit *object.Commit) (bool, error) {
+	for i := range commit.ParentHashes {
+		parent, err := commit.Parent(i)
+		if err != nil {
+			return false, fmt.Errorf("unable to retrieve parent commit: %v", err)
+		}
+		slog.Debug("Parent commit", "hash", parent.Hash)
+		changed, err := d.hasDiff(commit, parent)
+		if err != nil || changed {
+			return changed, err
+		}
+	}
+	return false, nil
+}
+
+// HasDiff returns true if the source pathes differ between two commits, see sourceDiffer
+func HasDiff(current *object.Commit, ancestor *object.Commit, root string, pathes []string) (bool, error) {
+	d, err := newSourceDiffer(root, pathes)
+	if err != nil {
+		return false, err
+	}
+	return d.hasDiff(current, ancestor)
+}
+
+// IsCodeChange returns true if the source code of a commit differs from the one of its parents,
+// a merge commit is a code change if its source code differs from the one of any of its parents,
+// so that an image built on one side of the merge is not reused for a merge which brings source changes from the other side
+func IsCodeChange(commit *object.Commit, root string, pathes []string) (bool, error) {
+	d, err := newSourceDiffer(root, pathes)
+	if err != nil {
+		return false, err
+	}
+	return d.isCodeChange(commit)
+}
+
 // FindCodeChange returns:
 //   - the latest ancestor commit for which the source code has changed, then list of following commits for which the source code has not changed
 //   - an error if any
+//
+// The history is followed through the first parent of each commit, and merge commits are compared with all their parents, see IsCodeChange
 func FindCodeChange(repository *git.Repository, fromHash plumbing.Hash, pathes []string) ([]plumbing.Hash, error) {
 	current, err := repository.CommitObject(fromHash)
 	if err != nil {
@@ -69,25 +180,76 @@ func FindCodeChange(repository *git.Repository, fromHash plumbing.Hash, pathes [
 		slog.Error("Unable to get repository root", "error", err)
 		return nil, err
 	}
+	d, err := newSourceDiffer(root, pathes)
+	if err != nil {
+		return nil, err
+	}
 
-	var parent *object.Commit
 	for len(current.ParentHashes) != 0 {
-		parent, err = current.Parent(0)
-		if err != nil {
-			return hashes, fmt.Errorf("unable to retrieve parent commit: %v", err)
-		}
-		slog.Info("Current commit", "hash", current.Hash)
-		slog.Info("Parent commit", "hash", parent.Hash)
-		changed, err := HasDiff(current, parent, root, pathes)
+		slog.Debug("Current commit", "hash", current.Hash)
+		changed, err := d.isCodeChange(current)
 		if err != nil {
 			return hashes, err
-		} else if changed {
-			hashes = append(hashes, current.Hash)
-			break
 		}
 		hashes = append(hashes, current.Hash)
-		current = parent
+		if changed {
+			break
+		}
+		current, err = current.Parent(0)
+		if err != nil {
+			return hashes, fmt.Errorf("unable to retrieve parent commit: %v", err)
+		}
 	}
 	slices.Reverse(hashes)
 	return hashes, nil
 }
+
+// GetSourceTreeHash returns a hash of the source pathes content for a commit, computed from the git object hashes and modes
+// of the source pathes, so it does not depend on the commit history: two commits with the same source pathes content,
+// on any branch, have the same hash. Empty source pathes means the whole repository.
+// With patterns in the source pathes, the hash is computed from the matching files.
+func GetSourceTreeHash(repository *git.Repository, hash plumbing.Hash, pathes []string) (string, error) {
+	commit, err := repository.CommitObject(hash)
+	if err != nil {
+		return "", fmt.Errorf("unable to retrieve commit from hash: %v", err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", fmt.Errorf("unable to retrieve tree of commit %s: %v", hash, err)
+	}
+	if len(pathes) == 0 {
+		return tree.Hash.String(), nil
+	}
+	matcher, err := NewSourceMatcher(pathes)
+	if err != nil {
+		return "", err
+	}
+	if !matcher.IsPlain() {
+		// Patterns can not be resolved to tree objects, the matching files are hashed
+		h := sha256.New()
+		err = tree.Files().ForEach(func(f *object.File) error {
+			if matcher.Match(f.Name) {
+				fmt.Fprintf(h, "%s %s %s\n", f.Name, f.Mode, f.Hash)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("unable to list files of commit %s: %v", hash, err)
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	cleanPathes := []string{}
+	for _, p := range pathes {
+		cleanPathes = append(cleanPathes, path.Clean(p))
+	}
+	sort.Strings(cleanPathes)
+	h := sha256.New()
+	for _, p := range slices.Compact(cleanPathes) {
+		objectHash, mode, err := lookupSourcePath(tree, p)
+		if err != nil {
+			return "", fmt.Errorf("commit %s: %v", hash, err)
+		}
+		fmt.Fprintf(h, "%s %s %s\n", p, mode, objectHash)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
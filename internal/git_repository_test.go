@@ -1,11 +1,16 @@
 package internal
 
 import (
+	"encoding/binary"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/require"
 )
 
@@ -159,3 +164,229 @@ func TestFindCodeChange(t *testing.T) {
 	require.Contains(hashes, *hash3)
 	require.Contains(hashes, *hash4)
 }
+
+func TestFindCodeChangeMerge(t *testing.T) {
+	require := require.New(t)
+
+	gitObj, err := initGitRepo("ciux-merge-test-")
+	require.NoError(err)
+	repo := gitObj.Repository
+	root, err := gitObj.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+	worktree, err := repo.Worktree()
+	require.NoError(err)
+
+	checkout := func(branch string) {
+		err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch)})
+		require.NoError(err)
+	}
+	// merge creates a merge commit of the current branch, with the files of the merged branch
+	merge := func(message string, parent plumbing.Hash, files ...string) plumbing.Hash {
+		head, err := repo.Head()
+		require.NoError(err)
+		for _, file := range files {
+			require.NoError(os.WriteFile(filepath.Join(root, file), []byte{}, 0644))
+			_, err = worktree.Add(file)
+			require.NoError(err)
+		}
+		hash, err := worktree.Commit(message, &git.CommitOptions{Author: &author, Parents: []plumbing.Hash{head.Hash(), parent}, AllowEmptyCommits: true})
+		require.NoError(err)
+		return hash
+	}
+
+	require.NoError(os.MkdirAll(filepath.Join(root, rootfs), 0755))
+	_, _, err = gitObj.TaggedCommit("rootfs/file1.txt", "c1", "v1.0.0", true, author)
+	require.NoError(err)
+	require.NoError(gitObj.CreateBranch("feature"))
+	f1, _, err := gitObj.TaggedCommit("rootfs/file2.txt", "f1", "feature-1", false, author)
+	require.NoError(err)
+	checkout("master")
+	_, _, err = gitObj.TaggedCommit("file3.txt", "m1", "v1.1.0", true, author)
+	require.NoError(err)
+
+	// The merge brings source changes through its second parent
+	merge1 := merge("merge feature", *f1, "rootfs/file2.txt")
+	commit, err := repo.CommitObject(merge1)
+	require.NoError(err)
+	changed, err := IsCodeChange(commit, root, []string{rootfs})
+	require.NoError(err)
+	require.True(changed)
+	hashes, err := FindCodeChange(repo, merge1, []string{rootfs})
+	require.NoError(err)
+	require.Equal([]plumbing.Hash{merge1}, hashes)
+
+	// The merge brings no source change
+	require.NoError(gitObj.CreateBranch("docs"))
+	d1, _, err := gitObj.TaggedCommit("file4.txt", "d1", "docs-1", false, author)
+	require.NoError(err)
+	checkout("master")
+	m2, _, err := gitObj.TaggedCommit("file5.txt", "m2", "v1.2.0", true, author)
+	require.NoError(err)
+	merge2 := merge("merge docs", *d1, "file4.txt")
+	hashes, err = FindCodeChange(repo, merge2, []string{rootfs})
+	require.NoError(err)
+	require.Equal([]plumbing.Hash{merge1, *m2, merge2}, hashes)
+
+	// The merge of the main branch into the feature branch brings no source change to the feature branch
+	checkout("feature")
+	merge3 := merge("merge master", merge2, "file3.txt", "file4.txt", "file5.txt")
+	hashes, err = FindCodeChange(repo, merge3, []string{rootfs})
+	require.NoError(err)
+	require.Equal([]plumbing.Hash{*f1, merge3}, hashes)
+
+	// Source changes brought by the main branch are detected
+	hashes, err = FindCodeChange(repo, merge3, []string{"file3.txt"})
+	require.NoError(err)
+	require.Equal([]plumbing.Hash{merge3}, hashes)
+}
+
+func TestGetSourceTreeHash(t *testing.T) {
+	require := require.New(t)
+
+	gitObj, err := prepareTestRepository()
+	require.NoError(err)
+	repo := gitObj.Repository
+
+	hash1, err := repo.ResolveRevision("v1.0.0")
+	require.NoError(err)
+	hash2, err := repo.ResolveRevision("v2.0.0")
+	require.NoError(err)
+	hash3, err := repo.ResolveRevision("HEAD")
+	require.NoError(err)
+
+	treeHash := func(hash plumbing.Hash, pathes []string) string {
+		h, err := GetSourceTreeHash(repo, hash, pathes)
+		require.NoError(err)
+		return h
+	}
+
+	// file2.txt is outside of the source pathes
+	pathes := []string{rootfs}
+	require.Equal(treeHash(*hash1, pathes), treeHash(*hash2, pathes))
+	require.NotEqual(treeHash(*hash2, pathes), treeHash(*hash3, pathes))
+	require.Equal(treeHash(*hash1, pathes), treeHash(*hash1, []string{rootfs, rootfs}))
+
+	// Patterns
+	require.Equal(treeHash(*hash1, []string{"**/*.txt", "!file2.txt"}), treeHash(*hash2, []string{"**/*.txt", "!file2.txt"}))
+	require.NotEqual(treeHash(*hash1, []string{"**/*.txt"}), treeHash(*hash2, []string{"**/*.txt"}))
+
+	// Whole repository
+	require.NotEqual(treeHash(*hash1, nil), treeHash(*hash2, nil))
+	require.NotEqual(treeHash(*hash1, []string{""}), treeHash(*hash2, []string{""}))
+
+	// Missing source path
+	require.NotEqual(treeHash(*hash1, pathes), treeHash(*hash1, []string{rootfs, "missing"}))
+
+	// The mode is hashed, for plain source pathes as for patterns
+	root, err := gitObj.GetRoot()
+	require.NoError(err)
+	filename1 := filepath.Join(rootfs, "file1.txt")
+	require.NoError(os.Chmod(filepath.Join(root, filename1), 0755))
+	worktree, err := repo.Worktree()
+	require.NoError(err)
+	_, err = worktree.Add(filename1)
+	require.NoError(err)
+	hash4, err := worktree.Commit("make file1 executable", &git.CommitOptions{Author: &author})
+	require.NoError(err)
+	for _, p := range [][]string{pathes, {filename1}, {"**/*.txt"}} {
+		require.NotEqual(treeHash(*hash3, p), treeHash(hash4, p), "pathes=%v", p)
+	}
+}
+
+// generateBenchmarkRepository creates a repository with a source directory, changed in the second commit only,
+// and a data directory, changed in all the commits
+func generateBenchmarkRepository(b *testing.B, commits int) (*git.Repository, plumbing.Hash) {
+	root := b.TempDir()
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, dir := range []string{"src", "data"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	store := func(o interface {
+		Encode(plumbing.EncodedObject) error
+	}) plumbing.Hash {
+		obj := repo.Storer.NewEncodedObject()
+		if err := o.Encode(obj); err != nil {
+			b.Fatal(err)
+		}
+		hash, err := repo.Storer.SetEncodedObject(obj)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return hash
+	}
+	blob := func(content []byte) plumbing.Hash {
+		obj := repo.Storer.NewEncodedObject()
+		obj.SetType(plumbing.BlobObject)
+		w, err := obj.Writer()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := w.Write(content); err != nil {
+			b.Fatal(err)
+		}
+		w.Close()
+		hash, err := repo.Storer.SetEncodedObject(obj)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return hash
+	}
+
+	srcEntries := []object.TreeEntry{}
+	for i := 0; i < 50; i++ {
+		srcEntries = append(srcEntries, object.TreeEntry{Name: fmt.Sprintf("file%03d.py", i), Mode: filemode.Regular, Hash: blob([]byte(fmt.Sprintf("print(%d)\n", i)))})
+	}
+	initialSrcTree := store(&object.Tree{Entries: srcEntries[1:]})
+	srcTree := store(&object.Tree{Entries: srcEntries})
+
+	data := make([]byte, 4096)
+	var parent plumbing.Hash
+	for i := 0; i < commits; i++ {
+		binary.LittleEndian.PutUint64(data, uint64(i))
+		dataTree := store(&object.Tree{Entries: []object.TreeEntry{{Name: "data.bin", Mode: filemode.Regular, Hash: blob(data)}}})
+		src := srcTree
+		if i == 0 {
+			src = initialSrcTree
+		}
+		tree := store(&object.Tree{Entries: []object.TreeEntry{
+			{Name: "data", Mode: filemode.Dir, Hash: dataTree},
+			{Name: "src", Mode: filemode.Dir, Hash: src},
+		}})
+		commit := object.Commit{Author: author, Committer: author, Message: fmt.Sprintf("commit %d", i), TreeHash: tree}
+		if i != 0 {
+			commit.ParentHashes = []plumbing.Hash{parent}
+		}
+		parent = store(&commit)
+	}
+	return repo, parent
+}
+
+func benchmarkFindCodeChange(b *testing.B, pathes []string) {
+	commits := 2000
+	repo, head := generateBenchmarkRepository(b, commits)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hashes, err := FindCodeChange(repo, head, pathes)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(hashes) != commits-1 {
+			b.Fatalf("expected %d commits since the code change, got %d", commits-1, len(hashes))
+		}
+	}
+}
+
+func BenchmarkFindCodeChange(b *testing.B) {
+	benchmarkFindCodeChange(b, []string{"src"})
+}
+
+func BenchmarkFindCodeChangePatterns(b *testing.B) {
+	benchmarkFindCodeChange(b, []string{"**/*.py", "!data/"})
+}
@@ -1,6 +1,11 @@
 package internal
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
 
 type GitRevision struct {
 	Tag     string
@@ -13,7 +18,13 @@ type GitRevision struct {
 func (rev *GitRevision) IsRelease() bool {
 	if rev.Counter != 0 {
 		return false
-	} else if rev.Dirty {
+	}
+	return rev.IsPromotable()
+}
+
+// IsPromotable returns true if the revision is a clean commit of the main/master branch
+func (rev *GitRevision) IsPromotable() bool {
+	if rev.Dirty {
 		return false
 	} else if rev.Branch != "master" && rev.Branch != "main" {
 		return false
@@ -42,6 +53,41 @@ func (rev *GitRevision) GetVersion() string {
 	return version
 }
 
+var (
+	describeRegexp  = regexp.MustCompile(`^(.+)-(\d+)-g([0-9a-f]{7})$`)
+	shortHashRegexp = regexp.MustCompile(`^[0-9a-f]{7}$`)
+)
+
+// ParseGitRevision parses a version produced by GetVersion()
+// the returned revision has no branch, and its hash is abbreviated
+func ParseGitRevision(version string) (*GitRevision, error) {
+	rev := GitRevision{}
+	if strings.HasSuffix(version, "-dirty") {
+		rev.Dirty = true
+		version = strings.TrimSuffix(version, "-dirty")
+	}
+	if shortHashRegexp.MatchString(version) {
+		rev.Hash = version
+		return &rev, nil
+	}
+	matches := describeRegexp.FindStringSubmatch(version)
+	if matches != nil {
+		counter, err := strconv.Atoi(matches[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid commit counter in version %s: %v", version, err)
+		}
+		rev.Tag = matches[1]
+		rev.Counter = counter
+		rev.Hash = matches[3]
+	} else {
+		rev.Tag = version
+	}
+	if SemVerParse(rev.Tag) == nil {
+		return nil, fmt.Errorf("invalid semver tag %s in version %s", rev.Tag, version)
+	}
+	return &rev, nil
+}
+
 func (rev *GitRevision) UpgradeTag() (string, error) {
 	// Get the latest tag
 	tag := rev.Tag
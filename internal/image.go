@@ -1,54 +1,290 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 )
 
+// Errors returned by registry calls, use errors.Is() to check them
+var (
+	// ErrImageNotFound is returned if the image, or its repository, does not exist in the registry
+	ErrImageNotFound = errors.New("image not found")
+	// ErrRegistryUnauthorized is returned if the credentials are missing or invalid
+	ErrRegistryUnauthorized = errors.New("registry access denied")
+	// ErrRegistryUnreachable is returned for network, TLS or registry server errors
+	ErrRegistryUnreachable = errors.New("registry unreachable")
+)
+
+// classifyRegistryError returns ErrImageNotFound, ErrRegistryUnauthorized or ErrRegistryUnreachable for an error returned by a registry call
+// some registries, like ghcr.io, return an authorization error for missing repositories when accessed anonymously
+func classifyRegistryError(err error) error {
+	var transportErr *transport.Error
+	if !errors.As(err, &transportErr) {
+		return ErrRegistryUnreachable
+	}
+	for _, diagnostic := range transportErr.Errors {
+		switch diagnostic.Code {
+		case transport.ManifestUnknownErrorCode, transport.NameUnknownErrorCode, transport.BlobUnknownErrorCode:
+			return ErrImageNotFound
+		case transport.UnauthorizedErrorCode, transport.DeniedErrorCode:
+			return ErrRegistryUnauthorized
+		}
+	}
+	switch transportErr.StatusCode {
+	case http.StatusNotFound:
+		return ErrImageNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrRegistryUnauthorized
+	}
+	return ErrRegistryUnreachable
+}
+
+// UntestedTagSuffix is added to the tag of images which have not been validated by e2e tests
+const UntestedTagSuffix = "-untested"
+
 type Image struct {
 	InRegistry        bool
 	Registry          string
 	Name              string
 	Tag               string
 	TemporaryRegistry string
+	// True if the image tag has the untested suffix
+	Untested bool
+	// Digest of the image manifest, set if the image is in the registry
+	Digest string
+	// Required platforms which are not in the registry, the image is then not in the registry
+	MissingPlatforms []string
+	// Base images whose digest has changed since the image has been built, the image is then not in the registry
+	OutdatedBaseImages []string
 }
 
 func (i Image) String() string {
-	return fmt.Sprintf("%s, in registry: %t", i.Url(), i.InRegistry)
+	msg := fmt.Sprintf("%s, in registry: %t", i.Url(), i.InRegistry)
+	if i.Untested {
+		msg += ", untested: true"
+	}
+	if len(i.MissingPlatforms) != 0 {
+		msg += ", missing platforms: " + strings.Join(i.MissingPlatforms, ", ")
+	}
+	if len(i.OutdatedBaseImages) != 0 {
+		msg += ", outdated base images: " + strings.Join(i.OutdatedBaseImages, ", ")
+	}
+	return msg
+}
+
+// UntestedTag returns the untested variant of an image tag
+func UntestedTag(tag string) string {
+	return tag + UntestedTagSuffix
+}
+
+// IsUntestedTag returns true if an image tag is an untested tag
+func IsUntestedTag(tag string) bool {
+	return strings.HasSuffix(tag, UntestedTagSuffix)
+}
+
+// TestedTag returns the tested variant of an image tag
+func TestedTag(tag string) string {
+	return strings.TrimSuffix(tag, UntestedTagSuffix)
 }
 
 func (i Image) Url() string {
 	return fmt.Sprintf("%s/%s:%s", i.Registry, i.Name, i.Tag)
 }
 
+// PinnedUrl returns the reference of the image by digest, or an empty string if the digest is unknown
+func (i Image) PinnedUrl() string {
+	if i.Digest == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s@%s", i.Registry, i.Name, i.Digest)
+}
+
 func (i Image) Desc() (v1.Image, name.Reference, error) {
 	return DescImage(i.Url())
 }
 
 func ListTags(src string) ([]string, error) {
-	repo, err := name.NewRepository(src)
+	repo, err := NewRepository(src)
 	if err != nil {
 		return nil, fmt.Errorf("parsing repo %q: %w", src, err)
 	}
-	return remote.List(repo)
+	return remote.List(repo, remoteOptions(repo.RegistryStr())...)
 }
 
 func DescImage(r string) (v1.Image, name.Reference, error) {
-	ref, err := name.ParseReference(r)
+	ref, err := ParseReference(r)
 	if err != nil {
 		return nil, nil, fmt.Errorf("parsing reference %q: %w", r, err)
 	}
-	img, err := remote.Image(ref)
+	img, err := remote.Image(ref, remoteOptions(ref.Context().RegistryStr())...)
 	if err != nil {
-		return nil, nil, fmt.Errorf("reading image %q: %w", ref, err)
+		return nil, nil, fmt.Errorf("reading image %q: %w: %w", ref, classifyRegistryError(err), err)
 	}
 	return img, ref, nil
 }
 
+// GetImageDigest returns the digest of the manifest referenced by an image name,
+// for a multi-architecture image it is the digest of the image index
+func GetImageDigest(ref name.Reference) (string, error) {
+	desc, err := remote.Head(ref, remoteOptions(ref.Context().RegistryStr())...)
+	if err != nil {
+		return "", fmt.Errorf("reading image digest %q: %w: %w", ref, classifyRegistryError(err), err)
+	}
+	return desc.Digest.String(), nil
+}
+
+// GetImagePlatforms returns the platforms of an image, i.e. the platforms of the manifests of an image index,
+// or the platform of the configuration of a single-platform image
+func GetImagePlatforms(ref name.Reference) ([]v1.Platform, error) {
+	desc, err := remote.Get(ref, remoteOptions(ref.Context().RegistryStr())...)
+	if err != nil {
+		return nil, fmt.Errorf("reading image %q: %w: %w", ref, classifyRegistryError(err), err)
+	}
+	platforms := []v1.Platform{}
+	if desc.MediaType.IsIndex() {
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("reading image index %q: %w", ref, err)
+		}
+		manifest, err := index.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("reading image index manifest %q: %w", ref, err)
+		}
+		for _, m := range manifest.Manifests {
+			if m.Platform != nil {
+				platforms = append(platforms, *m.Platform)
+			}
+		}
+		return platforms, nil
+	}
+	img, err := desc.Image()
+	if err != nil {
+		return nil, fmt.Errorf("reading image %q: %w", ref, err)
+	}
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("reading image configuration %q: %w: %w", ref, classifyRegistryError(err), err)
+	}
+	if platform := config.Platform(); platform != nil {
+		platforms = append(platforms, *platform)
+	}
+	return platforms, nil
+}
+
+// MissingPlatforms returns the required platforms, e.g. linux/arm64, which are not provided by an image
+func MissingPlatforms(ref name.Reference, required []string) ([]string, error) {
+	missing := []string{}
+	if len(required) == 0 {
+		return missing, nil
+	}
+	platforms, err := GetImagePlatforms(ref)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range required {
+		spec, err := v1.ParsePlatform(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid platform %s: %v", r, err)
+		}
+		found := false
+		for _, platform := range platforms {
+			if platform.Satisfies(*spec) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, r)
+		}
+	}
+	return missing, nil
+}
+
+// BaseImagesLabel is the label of the project image which records the digests of its base images,
+// its value is CIUX_BASE_IMAGES, e.g. 'docker build --label io.k8s-school.ciux.base-images="$CIUX_BASE_IMAGES"'
+const BaseImagesLabel = "io.k8s-school.ciux.base-images"
+
+// FormatBaseImagesLabel returns the value of BaseImagesLabel for digests by base image name,
+// i.e. a comma-separated list of <image>@<digest>
+func FormatBaseImagesLabel(digests map[string]string) string {
+	entries := []string{}
+	for image, digest := range digests {
+		entries = append(entries, image+"@"+digest)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+// ParseBaseImagesLabel returns the digests by base image name recorded in BaseImagesLabel
+func ParseBaseImagesLabel(label string) map[string]string {
+	digests := map[string]string{}
+	for _, entry := range strings.Split(label, ",") {
+		sep := strings.LastIndex(entry, "@")
+		if sep == -1 {
+			continue
+		}
+		digests[strings.TrimSpace(entry[:sep])] = strings.TrimSpace(entry[sep+1:])
+	}
+	return digests
+}
+
+// GetImageLabels returns the labels of an image configuration,
+// for an image index it is the configuration of its first image
+func GetImageLabels(ref name.Reference) (map[string]string, error) {
+	desc, err := remote.Get(ref, remoteOptions(ref.Context().RegistryStr())...)
+	if err != nil {
+		return nil, fmt.Errorf("reading image %q: %w: %w", ref, classifyRegistryError(err), err)
+	}
+	var img v1.Image
+	if desc.MediaType.IsIndex() {
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("reading image index %q: %w", ref, err)
+		}
+		manifest, err := index.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("reading image index manifest %q: %w", ref, err)
+		}
+		if len(manifest.Manifests) == 0 {
+			return map[string]string{}, nil
+		}
+		img, err = index.Image(manifest.Manifests[0].Digest)
+		if err != nil {
+			return nil, fmt.Errorf("reading image %q of index %q: %w", manifest.Manifests[0].Digest, ref, err)
+		}
+	} else {
+		img, err = desc.Image()
+		if err != nil {
+			return nil, fmt.Errorf("reading image %q: %w", ref, err)
+		}
+	}
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("reading image configuration %q: %w: %w", ref, classifyRegistryError(err), err)
+	}
+	if config.Config.Labels == nil {
+		return map[string]string{}, nil
+	}
+	return config.Config.Labels, nil
+}
+
+// PinImage returns the reference by digest of an image, i.e. repository@sha256:...
+func PinImage(image string, digest string) (string, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse image name %s: %v", image, err)
+	}
+	return ref.Context().Name() + "@" + digest, nil
+}
+
 func GetImageEnVarPrefix(image string) (string, error) {
 	ref, err := name.ParseReference(image)
 	if err != nil {
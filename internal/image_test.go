@@ -1,11 +1,110 @@
 package internal
 
 import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	require "github.com/stretchr/testify/assert"
 )
 
+// pushMultiPlatformImage pushes an image index with one random image per platform to the given url
+func pushMultiPlatformImage(t *testing.T, url string, platforms ...string) v1.ImageIndex {
+	var index v1.ImageIndex = empty.Index
+	for _, p := range platforms {
+		platform, err := v1.ParsePlatform(p)
+		if err != nil {
+			t.Fatalf("unable to parse platform %s: %v", p, err)
+		}
+		img, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatalf("unable to create random image: %v", err)
+		}
+		index = mutate.AppendManifests(index, mutate.IndexAddendum{
+			Add:        img,
+			Descriptor: v1.Descriptor{Platform: platform},
+		})
+	}
+	ref, err := name.ParseReference(url)
+	if err != nil {
+		t.Fatalf("unable to parse reference %s: %v", url, err)
+	}
+	err = remote.WriteIndex(ref, index)
+	if err != nil {
+		t.Fatalf("unable to push image index %s: %v", url, err)
+	}
+	return index
+}
+
+// startTestRegistry starts an in-process registry and returns its host
+func startTestRegistry(t *testing.T) string {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+// startRegistryRejectingDeletes starts an in-process registry which, like registry:2, rejects the deletion of tags,
+// and of all manifests if rejectDigests is set, like registry:2 when deletion is disabled, and returns its host
+func startRegistryRejectingDeletes(t *testing.T, rejectDigests bool) string {
+	reg := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		elems := strings.Split(r.URL.Path, "/")
+		if r.Method == http.MethodDelete && len(elems) > 2 && elems[len(elems)-2] == "manifests" &&
+			(rejectDigests || !strings.HasPrefix(elems[len(elems)-1], "sha256:")) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			fmt.Fprint(w, `{"errors":[{"code":"UNSUPPORTED","message":"The operation is unsupported."}]}`)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+// pushRandomImage pushes a random image to the given url
+func pushRandomImage(t *testing.T, url string) v1.Image {
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatalf("unable to create random image: %v", err)
+	}
+	ref, err := name.ParseReference(url)
+	if err != nil {
+		t.Fatalf("unable to parse reference %s: %v", url, err)
+	}
+	err = remote.Write(ref, img)
+	if err != nil {
+		t.Fatalf("unable to push image %s: %v", url, err)
+	}
+	return img
+}
+
+func TestGetImageDigest(t *testing.T) {
+	assert := require.New(t)
+	host := startTestRegistry(t)
+	url := fmt.Sprintf("%s/test/image:v1.0.0", host)
+	img := pushRandomImage(t, url)
+	expectedDigest, err := img.Digest()
+	assert.NoError(err)
+
+	ref, err := name.ParseReference(url)
+	assert.NoError(err)
+	digest, err := GetImageDigest(ref)
+	assert.NoError(err)
+	assert.Equal(expectedDigest.String(), digest)
+}
+
 func TestListTags(t *testing.T) {
 	assert := require.New(t)
 	tags, err := ListTags("docker.io/library/alpine")
@@ -54,3 +153,58 @@ func TestGetImgEnVarPrefix(t *testing.T) {
 	assert.NoError(err)
 	assert.Equal(expectedPrefix, prefix)
 }
+
+func TestDescImageErrors(t *testing.T) {
+	assert := require.New(t)
+	host := startTestRegistry(t)
+
+	_, _, err := DescImage(fmt.Sprintf("%s/test/image:notexist", host))
+	assert.ErrorIs(err, ErrImageNotFound)
+
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer unauthorized.Close()
+	_, _, err = DescImage(fmt.Sprintf("%s/test/image:v1.0.0", strings.TrimPrefix(unauthorized.URL, "http://")))
+	assert.ErrorIs(err, ErrRegistryUnauthorized)
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	_, _, err = DescImage(fmt.Sprintf("%s/test/image:v1.0.0", strings.TrimPrefix(unreachable.URL, "http://")))
+	assert.ErrorIs(err, ErrRegistryUnreachable)
+}
+
+func TestMissingPlatforms(t *testing.T) {
+	require := require.New(t)
+
+	url := startTestRegistry(t)
+	pushMultiPlatformImage(t, url+"/multi:v1", "linux/amd64", "linux/arm64/v8")
+	ref, err := name.ParseReference(url + "/multi:v1")
+	require.NoError(err)
+
+	platforms, err := GetImagePlatforms(ref)
+	require.NoError(err)
+	require.Len(platforms, 2)
+
+	missing, err := MissingPlatforms(ref, []string{"linux/amd64", "linux/arm64"})
+	require.NoError(err)
+	require.Empty(missing)
+	missing, err = MissingPlatforms(ref, []string{"linux/amd64", "linux/arm64/v8", "linux/s390x"})
+	require.NoError(err)
+	require.Equal([]string{"linux/s390x"}, missing)
+
+	// Single-platform image
+	img, err := random.Image(1024, 1)
+	require.NoError(err)
+	img, err = mutate.ConfigFile(img, &v1.ConfigFile{OS: "linux", Architecture: "amd64"})
+	require.NoError(err)
+	singleRef, err := name.ParseReference(url + "/single:v1")
+	require.NoError(err)
+	require.NoError(remote.Write(singleRef, img))
+	missing, err = MissingPlatforms(singleRef, []string{"linux/amd64", "linux/arm64"})
+	require.NoError(err)
+	require.Equal([]string{"linux/arm64"}, missing)
+
+	_, err = MissingPlatforms(ref, []string{"linux/amd64/v1/extra"})
+	require.Error(err)
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// LintSourcePathes returns warnings when the sourcePathes of the .ciux file and the sources of the COPY and ADD
+// instructions of the Dockerfiles disagree, i.e. when a change does not trigger a rebuild or triggers a useless one.
+// Images whose source pathes are computed with autoSourcePathes are not checked.
+func (p *Project) LintSourcePathes() ([]string, error) {
+	root, err := p.GitMain.GetRoot()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get root of project repository: %v", err)
+	}
+	warnings := []string{}
+	if p.Config.AutoSourcePathes == "" {
+		w, err := lintSourcePathes(root, p.Config.SourcePathes, p.Config.GetDockerfile())
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, w...)
+	}
+	for _, c := range p.Config.Images {
+		if len(c.SourcePathes) == 0 || p.Config.AutoSourcePathes != "" {
+			continue
+		}
+		// Like in GetImagesNames, images without Dockerfile are built with the project one
+		dockerfile := c.Dockerfile
+		if dockerfile == "" {
+			dockerfile = p.Config.GetDockerfile()
+		}
+		w, err := lintSourcePathes(root, c.SourcePathes, dockerfile)
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, w...)
+	}
+	return warnings, nil
+}
+
+// lintSourcePathes compares the sourcePathes of an image with the sources of its Dockerfile
+func lintSourcePathes(root string, sourcePathes []string, dockerfile string) ([]string, error) {
+	if !FileExists(filepath.Join(root, dockerfile)) {
+		return []string{fmt.Sprintf("Dockerfile %s not found, unable to check sourcePathes", dockerfile)}, nil
+	}
+	sources, err := GetDockerfileSources(root, dockerfile)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := NewSourceMatcher(sourcePathes)
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := []string{}
+	if !matcher.Match(filepath.ToSlash(dockerfile)) {
+		warnings = append(warnings, fmt.Sprintf("Dockerfile %s is not in sourcePathes, its changes do not trigger a rebuild", dockerfile))
+	}
+	for _, source := range sources {
+		filePathes := []string{source.Path}
+		if source.IsPattern() {
+			matches, err := filepath.Glob(filepath.Join(root, source.Path))
+			if err != nil {
+				return nil, fmt.Errorf("invalid source %s: %v", source, err)
+			}
+			filePathes = []string{}
+			for _, match := range matches {
+				relPath, err := filepath.Rel(root, match)
+				if err != nil {
+					return nil, err
+				}
+				filePathes = append(filePathes, filepath.ToSlash(relPath))
+			}
+		}
+		for _, filePath := range filePathes {
+			if !matcher.Match(filePath) {
+				warnings = append(warnings, fmt.Sprintf("%s is not in sourcePathes, its changes do not trigger a rebuild", source))
+				break
+			}
+		}
+	}
+
+	dockerfilePathes, err := DockerfileSourcePathes(root, dockerfile)
+	if err != nil {
+		return nil, err
+	}
+	dockerfileMatcher, err := NewSourceMatcher(dockerfilePathes)
+	if err != nil {
+		return nil, err
+	}
+	for _, sourcePath := range sourcePathes {
+		if isSourcePattern(sourcePath) || dockerfileMatcher.Match(sourcePath) {
+			continue
+		}
+		partial := false
+		for _, source := range sources {
+			if strings.HasPrefix(source.Path, sourcePath+"/") {
+				partial = true
+				break
+			}
+		}
+		if partial {
+			warnings = append(warnings, fmt.Sprintf("source path %s is only partially copied by %s, some of its changes trigger a useless rebuild", sourcePath, dockerfile))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("source path %s is not copied by %s, its changes trigger a useless rebuild", sourcePath, dockerfile))
+		}
+	}
+	return warnings, nil
+}
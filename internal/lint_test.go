@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintSourcePathes(t *testing.T) {
+	require := require.New(t)
+
+	gitObj, err := initGitRepo("ciux-lint-test-")
+	require.NoError(err)
+	root, err := gitObj.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	for _, dir := range []string{"fink_broker", "bin", "deps", "docs"} {
+		require.NoError(os.MkdirAll(filepath.Join(root, dir), 0755))
+	}
+	require.NoError(os.WriteFile(filepath.Join(root, "deps", "requirements.txt"), []byte(""), 0644))
+	require.NoError(os.WriteFile(filepath.Join(root, "Dockerfile"), []byte("FROM python\nCOPY fink_broker bin /src/\nCOPY deps/requirements.txt /tmp/\n"), 0644))
+
+	project := Project{GitMain: &gitObj, Config: ProjConfig{SourcePathes: []string{"Dockerfile", "fink_broker", "bin", "deps/requirements.txt"}}}
+	warnings, err := project.LintSourcePathes()
+	require.NoError(err)
+	require.Empty(warnings)
+
+	project.Config.SourcePathes = []string{"fink_broker", "deps", "docs"}
+	warnings, err = project.LintSourcePathes()
+	require.NoError(err)
+	require.Equal([]string{
+		"Dockerfile Dockerfile is not in sourcePathes, its changes do not trigger a rebuild",
+		"COPY bin (Dockerfile:2) is not in sourcePathes, its changes do not trigger a rebuild",
+		"source path deps is only partially copied by Dockerfile, some of its changes trigger a useless rebuild",
+		"source path docs is not copied by Dockerfile, its changes trigger a useless rebuild",
+	}, warnings)
+
+	// Source pathes computed from the Dockerfile are not checked
+	project.Config.AutoSourcePathes = AutoSourcePathesDockerfile
+	warnings, err = project.LintSourcePathes()
+	require.NoError(err)
+	require.Empty(warnings)
+
+	project.Config = ProjConfig{Images: []ImageConfig{{Name: "tools", SourcePathes: []string{"bin"}, Dockerfile: "tools/Dockerfile"}}}
+	warnings, err = project.LintSourcePathes()
+	require.NoError(err)
+	require.Equal([]string{"Dockerfile tools/Dockerfile not found, unable to check sourcePathes"}, warnings)
+
+	// Images without Dockerfile are built with the project one
+	project.Config = ProjConfig{SourcePathes: []string{"Dockerfile", "fink_broker", "bin", "deps/requirements.txt"}, Images: []ImageConfig{{Name: "noscience", SourcePathes: []string{"fink_broker"}}}}
+	warnings, err = project.LintSourcePathes()
+	require.NoError(err)
+	require.Equal([]string{
+		"Dockerfile Dockerfile is not in sourcePathes, its changes do not trigger a rebuild",
+		"COPY bin (Dockerfile:2) is not in sourcePathes, its changes do not trigger a rebuild",
+		"COPY deps/requirements.txt (Dockerfile:3) is not in sourcePathes, its changes do not trigger a rebuild",
+	}, warnings)
+}
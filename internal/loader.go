@@ -0,0 +1,353 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// Runner is the kind of machine running the CI job
+type Runner string
+
+const (
+	// RunnerAuto detects the runner from the environment
+	RunnerAuto Runner = "auto"
+	// RunnerGitHubHosted is an ephemeral GitHub Actions runner, images built by previous jobs are shared as artifacts
+	RunnerGitHubHosted Runner = "github-hosted"
+	// RunnerSelfHosted is a persistent runner, images built by previous jobs are available in the local container runtime
+	RunnerSelfHosted Runner = "self-hosted"
+	// RunnerLocal is a developer workstation
+	RunnerLocal Runner = "local"
+)
+
+// DetectRunner returns the runner type, based on the environment variables set by GitHub Actions
+func DetectRunner() Runner {
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return RunnerLocal
+	}
+	// RUNNER_ENVIRONMENT is not set by old versions of the GitHub Actions runner,
+	// which were only used for GitHub-hosted runners
+	if os.Getenv("RUNNER_ENVIRONMENT") == "self-hosted" {
+		return RunnerSelfHosted
+	}
+	return RunnerGitHubHosted
+}
+
+// ParseRunner returns the runner type for the value of the --runner option
+// legacy values, i.e. GitHub Actions 'runs-on' labels like "ubuntu-latest" or "['self-hosted']", are still supported
+func ParseRunner(value string) (Runner, error) {
+	switch Runner(value) {
+	case "", RunnerAuto:
+		runner := DetectRunner()
+		slog.Debug("Detect runner", "runner", runner)
+		return runner, nil
+	case RunnerGitHubHosted, RunnerSelfHosted, RunnerLocal:
+		return Runner(value), nil
+	}
+	if strings.HasPrefix(value, "[") || strings.Contains(value, "-latest") || strings.HasPrefix(value, "ubuntu-") {
+		slog.Warn("Deprecated runner value, use 'auto', 'github-hosted', 'self-hosted' or 'local'", "runner", value)
+		if strings.Contains(value, "self-hosted") {
+			return RunnerSelfHosted, nil
+		}
+		return RunnerGitHubHosted, nil
+	}
+	return "", fmt.Errorf("unsupported runner %q, use 'auto', 'github-hosted', 'self-hosted' or 'local'", value)
+}
+
+// ImageLoader loads container images in a container runtime
+type ImageLoader interface {
+	// Name returns the name of the loader
+	Name() string
+	// Exists returns true if the image is available in the container runtime
+	Exists(imageUrl string) (bool, error)
+	// Load loads an image from a docker or OCI tarball, or from an OCI layout directory
+	Load(imageUrl string, archivePath string) error
+}
+
+// Names of the available image loaders
+const (
+	LoaderAuto       = "auto"
+	LoaderDocker     = "docker"
+	LoaderPodman     = "podman"
+	LoaderContainerd = "containerd"
+	LoaderKind       = "kind"
+)
+
+// ImageLoaders returns the names of the available image loaders
+func ImageLoaders() []string {
+	return []string{LoaderAuto, LoaderDocker, LoaderPodman, LoaderContainerd, LoaderKind}
+}
+
+// containerdNamespace is the containerd namespace used by kubernetes
+const containerdNamespace = "k8s.io"
+
+// NewImageLoader returns the image loader for a given name
+// 'auto' selects the first container runtime client found in the PATH among docker, podman and ctr
+// kindCluster is the name of the kind cluster, only used by the kind loader,
+// which imports images in kind nodes using the container engine found in the PATH
+func NewImageLoader(loaderName string, kindCluster string) (ImageLoader, error) {
+	switch loaderName {
+	case "", LoaderAuto:
+		for _, engine := range []string{LoaderDocker, LoaderPodman} {
+			if _, err := exec.LookPath(engine); err == nil {
+				return &engineLoader{engine: engine}, nil
+			}
+		}
+		if _, err := exec.LookPath("ctr"); err == nil {
+			return &containerdLoader{namespace: containerdNamespace}, nil
+		}
+		return nil, fmt.Errorf("no container runtime client found in PATH (docker, podman, ctr)")
+	case LoaderDocker, LoaderPodman:
+		return &engineLoader{engine: loaderName}, nil
+	case LoaderContainerd:
+		return &containerdLoader{namespace: containerdNamespace}, nil
+	case LoaderKind:
+		engine := LoaderDocker
+		if _, err := exec.LookPath(engine); err != nil {
+			engine = LoaderPodman
+		}
+		return &kindLoader{engine: engine, cluster: kindCluster}, nil
+	}
+	return nil, fmt.Errorf("unsupported image loader %q, use one of %s", loaderName, strings.Join(ImageLoaders(), ", "))
+}
+
+// engineLoader loads images in the docker or podman image store
+type engineLoader struct {
+	engine string
+}
+
+func (l *engineLoader) Name() string {
+	return l.engine
+}
+
+func (l *engineLoader) Exists(imageUrl string) (bool, error) {
+	if _, err := exec.LookPath(l.engine); err != nil {
+		return false, fmt.Errorf("container engine %s not found: %v", l.engine, err)
+	}
+	_, _, err := ExecCmdArgs(nil, false, l.engine, "image", "inspect", imageUrl)
+	return err == nil, nil
+}
+
+func (l *engineLoader) Load(imageUrl string, archivePath string) error {
+	tarballPath, cleanup, err := ImageTarball(imageUrl, archivePath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	stdout, _, err := ExecCmdArgs(nil, false, l.engine, "load", "--input", tarballPath)
+	if err != nil {
+		return err
+	}
+	slog.Debug("Image loaded", "loader", l.engine, "output", stdout)
+	return nil
+}
+
+// containerdLoader loads images in the containerd image store, using ctr
+type containerdLoader struct {
+	namespace string
+}
+
+func (l *containerdLoader) Name() string {
+	return LoaderContainerd
+}
+
+func (l *containerdLoader) Exists(imageUrl string) (bool, error) {
+	stdout, _, err := ExecCmdArgs(nil, false, "ctr", "-n", l.namespace, "images", "ls", "-q")
+	if err != nil {
+		return false, err
+	}
+	return hasContainerdImage(stdout, imageUrl)
+}
+
+func (l *containerdLoader) Load(imageUrl string, archivePath string) error {
+	tarballPath, cleanup, err := ImageTarball(imageUrl, archivePath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	_, _, err = ExecCmdArgs(nil, false, "ctr", "-n", l.namespace, "images", "import", "--all-platforms", tarballPath)
+	return err
+}
+
+// kindLoader loads images in the containerd image store of all the nodes of a kind cluster,
+// it does not require the kind binary
+type kindLoader struct {
+	engine  string
+	cluster string
+}
+
+func (l *kindLoader) Name() string {
+	return LoaderKind
+}
+
+// nodes returns the container names of the kind cluster nodes
+func (l *kindLoader) nodes() ([]string, error) {
+	stdout, _, err := ExecCmdArgs(nil, false, l.engine, "ps", "--filter", "label=io.x-k8s.kind.cluster="+l.cluster, "--format", "{{.Names}}")
+	if err != nil {
+		return nil, err
+	}
+	nodes := strings.Fields(stdout)
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no node found for kind cluster %s", l.cluster)
+	}
+	return nodes, nil
+}
+
+func (l *kindLoader) Exists(imageUrl string) (bool, error) {
+	nodes, err := l.nodes()
+	if err != nil {
+		return false, err
+	}
+	for _, node := range nodes {
+		stdout, _, err := ExecCmdArgs(nil, false, l.engine, "exec", node, "ctr", "-n", containerdNamespace, "images", "ls", "-q")
+		if err != nil {
+			return false, err
+		}
+		found, err := hasContainerdImage(stdout, imageUrl)
+		if err != nil || !found {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (l *kindLoader) Load(imageUrl string, archivePath string) error {
+	nodes, err := l.nodes()
+	if err != nil {
+		return err
+	}
+	tarballPath, cleanup, err := ImageTarball(imageUrl, archivePath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	for _, node := range nodes {
+		f, err := os.Open(tarballPath)
+		if err != nil {
+			return fmt.Errorf("unable to open image tarball %s: %v", tarballPath, err)
+		}
+		slog.Debug("Import image in kind node", "node", node, "image", imageUrl)
+		_, _, err = ExecCmdArgs(f, false, l.engine, "exec", "-i", node, "ctr", "-n", containerdNamespace, "images", "import", "--all-platforms", "--digests", "--snapshotter=overlayfs", "-")
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasContainerdImage returns true if the output of 'ctr images ls -q' contains the image,
+// containerd stores fully qualified image names, like docker.io/library/alpine:latest
+func hasContainerdImage(imageList string, imageUrl string) (bool, error) {
+	ref, err := name.ParseReference(imageUrl)
+	if err != nil {
+		return false, fmt.Errorf("parsing reference %q: %w", imageUrl, err)
+	}
+	fullName := ref.Context().Name() + ":" + ref.Identifier()
+	if _, ok := ref.(name.Digest); ok {
+		fullName = ref.Context().Name() + "@" + ref.Identifier()
+	}
+	fullName = strings.Replace(fullName, name.DefaultRegistry+"/", "docker.io/", 1)
+	return slices.Contains(strings.Fields(imageList), fullName), nil
+}
+
+// ImageTarball returns the path to a tarball for the image which can be loaded by docker, podman or ctr
+// an OCI layout directory is converted to a temporary tarball, tagged with imageUrl,
+// cleanup() removes this temporary tarball
+func ImageTarball(imageUrl string, archivePath string) (string, func(), error) {
+	noop := func() {}
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return "", noop, fmt.Errorf("image archive %s not found: %v", archivePath, err)
+	}
+	if !info.IsDir() {
+		return archivePath, noop, nil
+	}
+
+	img, err := imageFromLayout(imageUrl, archivePath)
+	if err != nil {
+		return "", noop, err
+	}
+	ref, err := name.ParseReference(imageUrl)
+	if err != nil {
+		return "", noop, fmt.Errorf("parsing reference %q: %w", imageUrl, err)
+	}
+	f, err := os.CreateTemp("", "ciux-image-*.tar")
+	if err != nil {
+		return "", noop, fmt.Errorf("unable to create temporary tarball: %v", err)
+	}
+	f.Close()
+	cleanup := func() { os.Remove(f.Name()) }
+	slog.Debug("Convert OCI layout to tarball", "layout", archivePath, "tarball", f.Name())
+	err = tarball.WriteToFile(f.Name(), ref, img)
+	if err != nil {
+		cleanup()
+		return "", noop, fmt.Errorf("unable to write image tarball %s: %v", f.Name(), err)
+	}
+	return f.Name(), cleanup, nil
+}
+
+// imageFromLayout returns the image of an OCI layout directory
+// the image is selected with its 'org.opencontainers.image.ref.name' annotation, if the layout contains several images,
+// multi-architecture images are resolved for the current platform
+func imageFromLayout(imageUrl string, layoutPath string) (v1.Image, error) {
+	if _, err := os.Stat(filepath.Join(layoutPath, "oci-layout")); err != nil {
+		return nil, fmt.Errorf("directory %s is not an OCI layout: %v", layoutPath, err)
+	}
+	idx, err := layout.ImageIndexFromPath(layoutPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read OCI layout %s: %v", layoutPath, err)
+	}
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read OCI layout index %s: %v", layoutPath, err)
+	}
+	var desc *v1.Descriptor
+	if len(manifest.Manifests) == 1 {
+		desc = &manifest.Manifests[0]
+	} else {
+		tag := imageUrl
+		if ref, err := name.ParseReference(imageUrl); err == nil {
+			tag = ref.Identifier()
+		}
+		for i, m := range manifest.Manifests {
+			refName := m.Annotations["org.opencontainers.image.ref.name"]
+			if refName == imageUrl || refName == tag {
+				desc = &manifest.Manifests[i]
+				break
+			}
+		}
+	}
+	if desc == nil {
+		return nil, fmt.Errorf("image %s not found in OCI layout %s", imageUrl, layoutPath)
+	}
+	if !desc.MediaType.IsIndex() {
+		return idx.Image(desc.Digest)
+	}
+
+	childIdx, err := idx.ImageIndex(desc.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read image index %s: %v", desc.Digest, err)
+	}
+	childManifest, err := childIdx.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read image index %s: %v", desc.Digest, err)
+	}
+	platform := v1.Platform{OS: "linux", Architecture: runtime.GOARCH}
+	for _, m := range childManifest.Manifests {
+		if m.Platform != nil && m.Platform.Satisfies(platform) {
+			return childIdx.Image(m.Digest)
+		}
+	}
+	return nil, fmt.Errorf("no image for platform %s in OCI layout %s", platform.String(), layoutPath)
+}
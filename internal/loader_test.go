@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRunner(t *testing.T) {
+	require := require.New(t)
+
+	tests := []struct {
+		value             string
+		githubActions     string
+		runnerEnvironment string
+		expected          Runner
+	}{
+		{"", "", "", RunnerLocal},
+		{"auto", "true", "", RunnerGitHubHosted},
+		{"auto", "true", "github-hosted", RunnerGitHubHosted},
+		{"auto", "true", "self-hosted", RunnerSelfHosted},
+		{"self-hosted", "true", "github-hosted", RunnerSelfHosted},
+		{"ubuntu-latest", "", "", RunnerGitHubHosted},
+		{"['self-hosted']", "", "", RunnerSelfHosted},
+	}
+	for _, tt := range tests {
+		t.Setenv("GITHUB_ACTIONS", tt.githubActions)
+		t.Setenv("RUNNER_ENVIRONMENT", tt.runnerEnvironment)
+		runner, err := ParseRunner(tt.value)
+		require.NoError(err)
+		require.Equal(tt.expected, runner, "runner value: %s", tt.value)
+	}
+
+	_, err := ParseRunner("windows")
+	require.Error(err)
+}
+
+func TestNewImageLoader(t *testing.T) {
+	require := require.New(t)
+
+	for _, loaderName := range []string{LoaderDocker, LoaderPodman, LoaderContainerd, LoaderKind} {
+		loader, err := NewImageLoader(loaderName, "kind")
+		require.NoError(err)
+		require.Equal(loaderName, loader.Name())
+	}
+	_, err := NewImageLoader("nerdctl", "kind")
+	require.Error(err)
+}
+
+func TestImageTarball(t *testing.T) {
+	require := require.New(t)
+	imageUrl := "registry.io/project:v1.0.0"
+	dir := t.TempDir()
+
+	// Tarball is used as is
+	tarballPath := filepath.Join(dir, "image.tar")
+	require.NoError(os.WriteFile(tarballPath, []byte{}, 0644))
+	path, cleanup, err := ImageTarball(imageUrl, tarballPath)
+	require.NoError(err)
+	cleanup()
+	require.Equal(tarballPath, path)
+
+	// OCI layout with several images
+	img, err := random.Image(1024, 1)
+	require.NoError(err)
+	other, err := random.Image(1024, 1)
+	require.NoError(err)
+	layoutPath := filepath.Join(dir, "layout")
+	p, err := layout.Write(layoutPath, empty.Index)
+	require.NoError(err)
+	require.NoError(p.AppendImage(other, layout.WithAnnotations(map[string]string{"org.opencontainers.image.ref.name": "v0.9.0"})))
+	require.NoError(p.AppendImage(img, layout.WithAnnotations(map[string]string{"org.opencontainers.image.ref.name": "v1.0.0"})))
+
+	path, cleanup, err = ImageTarball(imageUrl, layoutPath)
+	require.NoError(err)
+	defer cleanup()
+	ref, err := name.NewTag(imageUrl)
+	require.NoError(err)
+	loaded, err := tarball.ImageFromPath(path, &ref)
+	require.NoError(err)
+	expectedDigest, err := img.Digest()
+	require.NoError(err)
+	digest, err := loaded.Digest()
+	require.NoError(err)
+	require.Equal(expectedDigest, digest)
+	cleanup()
+	require.NoFileExists(path)
+
+	// Multi-architecture image
+	idx := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: other, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "riscv64"}}},
+		mutate.IndexAddendum{Add: img, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: runtime.GOARCH}}},
+	)
+	multiArchPath := filepath.Join(dir, "multiarch")
+	p, err = layout.Write(multiArchPath, empty.Index)
+	require.NoError(err)
+	require.NoError(p.AppendIndex(idx))
+	platformImg, err := imageFromLayout(imageUrl, multiArchPath)
+	require.NoError(err)
+	digest, err = platformImg.Digest()
+	require.NoError(err)
+	require.Equal(expectedDigest, digest)
+
+	_, _, err = ImageTarball(imageUrl, filepath.Join(dir, "notexist"))
+	require.Error(err)
+}
+
+func TestHasContainerdImage(t *testing.T) {
+	require := require.New(t)
+	imageList := "docker.io/library/alpine:3.19\nregistry.io/project:v1.0.0\n"
+
+	for url, expected := range map[string]bool{
+		"alpine:3.19":                true,
+		"registry.io/project:v1.0.0": true,
+		"registry.io/project:v2.0.0": false,
+	} {
+		found, err := hasContainerdImage(imageList, url)
+		require.NoError(err)
+		require.Equal(expected, found, url)
+	}
+}
@@ -0,0 +1,242 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const LockFileApiVersion = "v1alpha1"
+
+// LockFile records the exact state resolved by 'ciux ignite'
+// for the main project and all its selected dependencies
+type LockFile struct {
+	ApiVersion   string             `yaml:"apiVersion"`
+	Project      string             `yaml:"project"`
+	Selector     string             `yaml:"selector"`
+	Main         LockedGit          `yaml:"main"`
+	Image        LockedImage        `yaml:"image"`
+	Images       []LockedImage      `yaml:"images,omitempty"`
+	Dependencies []LockedDependency `yaml:"dependencies"`
+}
+
+// LockedGit is the resolved revision of a git repository
+type LockedGit struct {
+	Url        string `yaml:"url,omitempty"`
+	WorkBranch string `yaml:"workBranch,omitempty"`
+	Hash       string `yaml:"hash,omitempty"`
+	Version    string `yaml:"version,omitempty"`
+	Dirty      bool   `yaml:"dirty,omitempty"`
+	// Tag, branch or commit the repository is pinned to, and the semver range it has been resolved from
+	Ref          string `yaml:"ref,omitempty"`
+	VersionRange string `yaml:"versionRange,omitempty"`
+}
+
+// LockedImage is the container image computed for the main project
+type LockedImage struct {
+	Url        string `yaml:"url"`
+	Registry   string `yaml:"registry"`
+	Name       string `yaml:"name"`
+	Tag        string `yaml:"tag"`
+	InRegistry bool   `yaml:"inRegistry"`
+	Digest     string `yaml:"digest,omitempty"`
+}
+
+// LockedDependency is the resolved state of a dependency,
+// PackageVersion is the module version of the installed Go package, read from the build information of its binary
+type LockedDependency struct {
+	Git            *LockedGit `yaml:"git,omitempty"`
+	Clone          bool       `yaml:"clone,omitempty"`
+	Pull           bool       `yaml:"pull,omitempty"`
+	Image          string     `yaml:"image,omitempty"`
+	ImageMirror    string     `yaml:"imageMirror,omitempty"`
+	ImageDigest    string     `yaml:"imageDigest,omitempty"`
+	Package        string     `yaml:"package,omitempty"`
+	PackageVersion string     `yaml:"packageVersion,omitempty"`
+}
+
+// NewLockFile builds the lock file content from the current project state
+// it must be called after dependencies have been retrieved and their images checked
+func (p *Project) NewLockFile() (LockFile, error) {
+	name, err := p.GetName()
+	if err != nil {
+		return LockFile{}, fmt.Errorf("unable to get project name: %v", err)
+	}
+	main, err := newLockedGit(p.GitMain)
+	if err != nil {
+		return LockFile{}, fmt.Errorf("unable to lock project main repository: %v", err)
+	}
+	lock := LockFile{
+		ApiVersion: LockFileApiVersion,
+		Project:    name,
+		Selector:   p.Selector.String(),
+		Main:       *main,
+		Image:      newLockedImage(p.Image),
+	}
+	for _, image := range p.Images {
+		lock.Images = append(lock.Images, newLockedImage(image))
+	}
+	for _, dep := range p.Dependencies {
+		lockedDep := LockedDependency{
+			Clone:       dep.Clone,
+			Pull:        dep.Pull,
+			ImageDigest: dep.ImageDigest,
+		}
+		if dep.Package != "" {
+			lockedDep.Package = dep.Package
+			lockedDep.PackageVersion = dep.PackageVersion
+		} else if dep.Git != nil {
+			lockedDep.Git, err = newLockedGit(dep.Git)
+			if err != nil {
+				return LockFile{}, fmt.Errorf("unable to lock dependency %s: %v", dep.Git.Url, err)
+			}
+		}
+		if dep.Image != "" || (dep.Git != nil && dep.Pull) {
+			lockedDep.Image, err = dep.GetCanonicalImageName(p.ImageRegistry)
+			if err != nil {
+				return LockFile{}, fmt.Errorf("unable to get image name for dependency %s: %v", dep, err)
+			}
+			mirror, err := p.Mirrors.Rewrite(lockedDep.Image)
+			if err != nil {
+				return LockFile{}, err
+			}
+			if mirror != lockedDep.Image {
+				lockedDep.ImageMirror = mirror
+			}
+		}
+		lock.Dependencies = append(lock.Dependencies, lockedDep)
+	}
+	return lock, nil
+}
+
+func newLockedImage(image Image) LockedImage {
+	return LockedImage{
+		Url:        image.Url(),
+		Registry:   image.Registry,
+		Name:       image.Name,
+		Tag:        image.Tag,
+		InRegistry: image.InRegistry,
+		Digest:     image.Digest,
+	}
+}
+
+// toImage returns the image recorded in the lock file
+func (locked LockedImage) toImage() Image {
+	return Image{
+		Registry:   locked.Registry,
+		Name:       locked.Name,
+		Tag:        locked.Tag,
+		InRegistry: locked.InRegistry,
+		Digest:     locked.Digest,
+	}
+}
+
+func newLockedGit(gitObj *Git) (*LockedGit, error) {
+	locked := LockedGit{
+		Url:        gitObj.Url,
+		WorkBranch: gitObj.WorkBranch,
+		Hash:       gitObj.RemoteHash,
+
+		Ref:          gitObj.Ref,
+		VersionRange: gitObj.VersionRange,
+	}
+	if !gitObj.isRemoteOnly() {
+		rev, err := gitObj.GetHeadRevision()
+		if err != nil {
+			return nil, fmt.Errorf("unable to describe git repository: %v", err)
+		}
+		locked.Hash = rev.Hash
+		locked.Version = rev.GetVersion()
+		locked.Dirty = rev.Dirty
+	}
+	return &locked, nil
+}
+
+// GetLockFilepath returns the path of the lock file for the current label selector
+func (p *Project) GetLockFilepath() (string, error) {
+	ciuxCfgDir, err := p.GetCiuxConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to get ciux config directory: %v", err)
+	}
+	lockFileName := "ciux" + LabelSelectorToFileName(p.Selector) + ".lock.yaml"
+	return filepath.Join(ciuxCfgDir, lockFileName), nil
+}
+
+// WriteLockFile writes out the lock file in the ciux configuration directory
+func (p *Project) WriteLockFile() (string, error) {
+	lock, err := p.NewLockFile()
+	if err != nil {
+		return "", err
+	}
+	lockFilepath, err := p.GetLockFilepath()
+	if err != nil {
+		return "", fmt.Errorf("unable to get lock file path: %v", err)
+	}
+	err = os.MkdirAll(filepath.Dir(lockFilepath), 0755)
+	if err != nil {
+		return "", fmt.Errorf("unable to create directory for lock file %s: %v", lockFilepath, err)
+	}
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return "", fmt.Errorf("unable to serialize lock file: %v", err)
+	}
+	err = os.WriteFile(lockFilepath, data, 0644)
+	if err != nil {
+		return "", fmt.Errorf("unable to write lock file %s: %v", lockFilepath, err)
+	}
+	slog.Debug("Write lock file", "file", lockFilepath)
+	msg := fmt.Sprintf("Lock file:\n  %s", lockFilepath)
+	return msg, nil
+}
+
+// ReadLockFile reads a lock file written by WriteLockFile
+func ReadLockFile(lockFilepath string) (LockFile, error) {
+	lock := LockFile{}
+	data, err := os.ReadFile(lockFilepath)
+	if err != nil {
+		return lock, fmt.Errorf("unable to read lock file %s: %v", lockFilepath, err)
+	}
+	err = yaml.Unmarshal(data, &lock)
+	if err != nil {
+		return lock, fmt.Errorf("unable to parse lock file %s: %v", lockFilepath, err)
+	}
+	if lock.ApiVersion != LockFileApiVersion {
+		return lock, fmt.Errorf("unsupported lock file version %q in %s", lock.ApiVersion, lockFilepath)
+	}
+	return lock, nil
+}
+
+// CheckDigests checks that the digests of the images resolved for the project
+// are the ones recorded in a previous lock file, images which were not recorded are ignored
+func (p *Project) CheckDigests(previous LockFile) error {
+	recorded := map[string]string{}
+	for _, locked := range previous.Dependencies {
+		if locked.Image != "" && locked.ImageDigest != "" {
+			recorded[locked.Image] = locked.ImageDigest
+		}
+	}
+	mismatches := []string{}
+	for _, dep := range p.Dependencies {
+		if dep.ImageDigest == "" {
+			continue
+		}
+		image, err := dep.GetCanonicalImageName(p.ImageRegistry)
+		if err != nil {
+			return fmt.Errorf("unable to get image name for dependency %s: %v", dep, err)
+		}
+		if digest, ok := recorded[image]; ok && digest != dep.ImageDigest {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %s, recorded %s", image, dep.ImageDigest, digest))
+		}
+	}
+	if p.Image.Digest != "" && previous.Image.Digest != "" && p.Image.Url() == previous.Image.Url && p.Image.Digest != previous.Image.Digest {
+		mismatches = append(mismatches, fmt.Sprintf("%s: %s, recorded %s", p.Image.Url(), p.Image.Digest, previous.Image.Digest))
+	}
+	if len(mismatches) != 0 {
+		return fmt.Errorf("image digests changed since the lock file was written:\n  %s", strings.Join(mismatches, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteLockFile(t *testing.T) {
+	require := require.New(t)
+
+	localGit, remoteGitDeps, _, err := setupTestProject("ciux-writelockfile-test-")
+	require.NoError(err)
+	root, err := localGit.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+	depRoot, err := remoteGitDeps[0].GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(depRoot)
+
+	project, err := NewProject(root, "", false, "build=true")
+	require.NoError(err)
+	tmpDir, err := os.MkdirTemp("", "ciux-writelockfile-test-projectdeps-")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+	err = project.RetrieveDepsSources(tmpDir)
+	require.NoError(err)
+	err = project.GetImageName("", false)
+	require.NoError(err)
+
+	_, err = project.WriteLockFile()
+	require.NoError(err)
+
+	lockFilepath, err := project.GetLockFilepath()
+	require.NoError(err)
+	require.Equal(filepath.Join(root, ".ciux.d", "ciux_build_true.lock.yaml"), lockFilepath)
+
+	lock, err := ReadLockFile(lockFilepath)
+	require.NoError(err)
+	require.Equal(LockFileApiVersion, lock.ApiVersion)
+	require.Equal("build=true", lock.Selector)
+
+	head, err := localGit.Repository.Head()
+	require.NoError(err)
+	require.Equal(head.Hash().String(), lock.Main.Hash)
+	require.Equal("v1.0.0", lock.Main.Version)
+	require.Equal("master", lock.Main.WorkBranch)
+	require.Equal(project.Image.Url(), lock.Image.Url)
+	require.False(lock.Image.InRegistry)
+
+	require.Len(lock.Dependencies, 2)
+	depHead, err := remoteGitDeps[0].Repository.Head()
+	require.NoError(err)
+	lockedDep := lock.Dependencies[0]
+	require.NotNil(lockedDep.Git)
+	require.Equal("file://"+depRoot, lockedDep.Git.Url)
+	require.Equal("master", lockedDep.Git.WorkBranch)
+	require.Equal(depHead.Hash().String(), lockedDep.Git.Hash)
+	require.Equal("v1.0.0", lockedDep.Git.Version)
+	require.True(lockedDep.Clone)
+	require.True(lockedDep.Pull)
+	require.Contains(lockedDep.Image, "test-registry.io/")
+}
+
+func TestReadLockFileVersion(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := os.MkdirTemp("", "ciux-readlockfile-test-")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	lockFilepath := filepath.Join(tmpDir, "ciux.lock.yaml")
+	err = os.WriteFile(lockFilepath, []byte("apiVersion: v0\nproject: test\n"), 0644)
+	require.NoError(err)
+
+	_, err = ReadLockFile(lockFilepath)
+	require.Error(err)
+}
+
+func TestCheckDigests(t *testing.T) {
+	require := require.New(t)
+
+	digest1 := "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	digest2 := "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	project := Project{
+		Dependencies: []*Dependency{
+			{Image: "alpine:3.19", ImageDigest: digest1},
+			{Image: "busybox:1.36", ImageDigest: digest1},
+		},
+		Image: Image{Registry: "test-registry.io", Name: "ciux", Tag: "v1.0.0", InRegistry: true, Digest: digest1},
+	}
+	previous := LockFile{
+		Image: LockedImage{Url: "test-registry.io/ciux:v1.0.0", Digest: digest1},
+		Dependencies: []LockedDependency{
+			{Image: "alpine:3.19", ImageDigest: digest1},
+		},
+	}
+	require.NoError(project.CheckDigests(previous))
+
+	previous.Dependencies[0].ImageDigest = digest2
+	err := project.CheckDigests(previous)
+	require.Error(err)
+	require.Contains(err.Error(), "alpine:3.19")
+
+	previous.Dependencies[0].ImageDigest = digest1
+	previous.Image.Digest = digest2
+	err = project.CheckDigests(previous)
+	require.Error(err)
+	require.Contains(err.Error(), "test-registry.io/ciux:v1.0.0")
+
+	// Image tag has changed, it is a different image
+	previous.Image.Url = "test-registry.io/ciux:v0.9.0"
+	require.NoError(project.CheckDigests(previous))
+}
+
+func TestGoPackageVersion(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("ktbx", goBinaryName("github.com/k8s-school/ktbx"))
+	require.Equal("ciux", goBinaryName("github.com/k8s-school/ciux/v2"))
+	require.Equal("finkctl", goBinaryName("github.com/astrolabsoftware/finkctl/v3/cmd/finkctl"))
+	require.Equal("v2", goBinaryName("v2"))
+
+	// The test binary is a development build
+	executable, err := os.Executable()
+	require.NoError(err)
+	version, err := readGoBinaryVersion(executable)
+	require.NoError(err)
+	require.Empty(version)
+
+	notBinary := filepath.Join(t.TempDir(), "ktbx")
+	require.NoError(os.WriteFile(notBinary, []byte("#!/bin/sh\n"), 0755))
+	_, err = readGoBinaryVersion(notBinary)
+	require.Error(err)
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// MirrorConfig rewrites the images of a registry, or of a registry path, to a mirror
+type MirrorConfig struct {
+	// Registry, with optional path, e.g. docker.io or gcr.io/distroless
+	Registry string `mapstructure:"registry" default:""`
+	// Mirror which replaces the registry, e.g. mirror.internal/dockerhub
+	Mirror string `mapstructure:"mirror" default:""`
+}
+
+// Mirrors is the list of registry mirrors of a project
+type Mirrors []MirrorConfig
+
+// Rewrite returns the image name on its mirror, or the image name itself if no mirror is defined for its registry
+// the image name is fully qualified before being rewritten, e.g. alpine:3.19 is index.docker.io/library/alpine:3.19,
+// so a docker.io mirror receives library/alpine:3.19, and the mirror with the longest matching registry path is used
+func (m Mirrors) Rewrite(image string) (string, error) {
+	if len(m) == 0 {
+		return image, nil
+	}
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse image name %s: %v", image, err)
+	}
+	repository := ref.Context().Name()
+
+	var best *MirrorConfig
+	var bestPrefix string
+	for i, mirror := range m {
+		prefix, err := normalizeRegistryPath(mirror.Registry)
+		if err != nil {
+			return "", err
+		}
+		if repository != prefix && !strings.HasPrefix(repository, prefix+"/") {
+			continue
+		}
+		if best == nil || len(prefix) > len(bestPrefix) {
+			best = &m[i]
+			bestPrefix = prefix
+		}
+	}
+	if best == nil {
+		return image, nil
+	}
+
+	separator := ":"
+	if _, ok := ref.(name.Digest); ok {
+		separator = "@"
+	}
+	mirrored := strings.TrimSuffix(best.Mirror, "/") + strings.TrimPrefix(repository, bestPrefix) + separator + ref.Identifier()
+	slog.Debug("Rewrite image to mirror", "image", image, "mirror", mirrored)
+	return mirrored, nil
+}
+
+// normalizeRegistryPath returns the fully qualified form of a registry with optional path
+func normalizeRegistryPath(registryPath string) (string, error) {
+	host, path, _ := strings.Cut(strings.TrimSuffix(registryPath, "/"), "/")
+	reg, err := name.NewRegistry(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid mirrored registry %s: %v", registryPath, err)
+	}
+	if path == "" {
+		return reg.RegistryStr(), nil
+	}
+	return reg.RegistryStr() + "/" + path, nil
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorsRewrite(t *testing.T) {
+	require := require.New(t)
+
+	mirrors := Mirrors{
+		{Registry: "docker.io", Mirror: "mirror.internal/dockerhub"},
+		{Registry: "gcr.io", Mirror: "mirror.internal/gcr/"},
+		{Registry: "gcr.io/distroless", Mirror: "mirror.internal/distroless"},
+	}
+	digest := "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"alpine:3.19", "mirror.internal/dockerhub/library/alpine:3.19"},
+		{"alpine", "mirror.internal/dockerhub/library/alpine:latest"},
+		{"docker.io/bitnami/kafka:3.6", "mirror.internal/dockerhub/bitnami/kafka:3.6"},
+		{"gcr.io/distroless/static:nonroot", "mirror.internal/distroless/static:nonroot"},
+		{"gcr.io/k8s-staging/pause:3.9", "mirror.internal/gcr/k8s-staging/pause:3.9"},
+		{"gcr.io/distroless-extra/base:1", "mirror.internal/gcr/distroless-extra/base:1"},
+		{"alpine@" + digest, "mirror.internal/dockerhub/library/alpine@" + digest},
+		{"quay.io/prometheus/node-exporter:v1.7.0", "quay.io/prometheus/node-exporter:v1.7.0"},
+	}
+	for _, tt := range tests {
+		got, err := mirrors.Rewrite(tt.image)
+		require.NoError(err, tt.image)
+		require.Equal(tt.want, got, tt.image)
+	}
+
+	got, err := Mirrors{}.Rewrite("alpine:3.19")
+	require.NoError(err)
+	require.Equal("alpine:3.19", got)
+
+	_, err = mirrors.Rewrite("INVALID IMAGE")
+	require.Error(err)
+}
+
+func TestMirrorsDependencyImage(t *testing.T) {
+	require := require.New(t)
+
+	url := startTestRegistry(t)
+	pushRandomImage(t, url+"/library/alpine:3.19")
+
+	project := Project{
+		Dependencies: []*Dependency{{Image: "alpine:3.19"}},
+		Mirrors:      Mirrors{{Registry: "docker.io", Mirror: url}},
+	}
+	images, err := project.CheckDepImages()
+	require.NoError(err)
+	require.Len(images, 1)
+	require.Equal(url+"/library/alpine:3.19", images[0].Name())
+	require.NotEmpty(project.Dependencies[0].ImageDigest)
+
+	vars, err := project.getImageDepConfigVars(project.Dependencies[0])
+	require.NoError(err)
+	values := map[string]string{}
+	for _, v := range vars {
+		values[v.Name] = v.Value
+	}
+	require.Equal(url+"/library/alpine:3.19", values["LIBRARY_ALPINE_IMAGE"])
+	require.Equal("alpine:3.19", values["LIBRARY_ALPINE_IMAGE_CANONICAL"])
+	require.Equal(project.Dependencies[0].ImageDigest, values["LIBRARY_ALPINE_IMAGE_DIGEST"])
+	require.Equal(url+"/library/alpine@"+project.Dependencies[0].ImageDigest, values["LIBRARY_ALPINE_IMAGE_PINNED"])
+}
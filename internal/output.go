@@ -0,0 +1,358 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const DefaultConfigFormat = "bash"
+
+// ConfigVar is a variable of the CIUXCONFIG file
+// a ConfigVar with an empty name is a comment line
+type ConfigVar struct {
+	Name    string
+	Value   string
+	Comment string
+}
+
+// ConfigWriter renders the CIUXCONFIG variables in a given format
+type ConfigWriter interface {
+	// Extension returns the extension of the CIUXCONFIG file
+	Extension() string
+	Write(w io.Writer, vars []ConfigVar) error
+}
+
+var configWriters = map[string]ConfigWriter{
+	"bash":          bashWriter{},
+	"dotenv":        dotenvWriter{},
+	"json":          jsonWriter{},
+	"yaml":          yamlWriter{},
+	"github-env":    githubEnvWriter{},
+	"gitlab-dotenv": gitlabDotenvWriter{},
+	"make":          makeWriter{},
+}
+
+// GetConfigWriter returns the writer for a CIUXCONFIG format
+// the default format is used if format is empty
+func GetConfigWriter(format string) (ConfigWriter, error) {
+	if format == "" {
+		format = DefaultConfigFormat
+	}
+	writer, ok := configWriters[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported configuration format %q, supported formats are %s", format, strings.Join(ConfigFormats(), ", "))
+	}
+	return writer, nil
+}
+
+// ConfigFormats returns the list of supported CIUXCONFIG formats
+func ConfigFormats() []string {
+	formats := []string{}
+	for format := range configWriters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+var safeValueRegexp = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]*$`)
+
+// shellQuote quotes a value for POSIX shells, safe values are not quoted
+func shellQuote(value string) string {
+	if safeValueRegexp.MatchString(value) {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+type bashWriter struct{}
+
+func (bashWriter) Extension() string {
+	return ".sh"
+}
+
+func (bashWriter) Write(w io.Writer, vars []ConfigVar) error {
+	for _, v := range vars {
+		if v.Comment != "" {
+			if _, err := fmt.Fprintf(w, "# %s\n", v.Comment); err != nil {
+				return err
+			}
+		}
+		if v.Name == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "export %s=%s\n", v.Name, shellQuote(v.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type dotenvWriter struct{}
+
+func (dotenvWriter) Extension() string {
+	return ".env"
+}
+
+func (dotenvWriter) Write(w io.Writer, vars []ConfigVar) error {
+	for _, v := range vars {
+		if v.Comment != "" {
+			if _, err := fmt.Fprintf(w, "# %s\n", v.Comment); err != nil {
+				return err
+			}
+		}
+		if v.Name == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", v.Name, dotenvQuote(v.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dotenvQuote quotes a value for dotenv files,
+// single quotes are used for literal values and double quotes for values which need escaping
+func dotenvQuote(value string) string {
+	if safeValueRegexp.MatchString(value) {
+		return value
+	}
+	if !strings.ContainsAny(value, "'\n") {
+		return "'" + value + "'"
+	}
+	var replacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+type jsonWriter struct{}
+
+func (jsonWriter) Extension() string {
+	return ".json"
+}
+
+// Write renders variables as a JSON object, in the order of the variables
+func (jsonWriter) Write(w io.Writer, vars []ConfigVar) error {
+	lines := []string{}
+	for _, v := range vars {
+		if v.Name == "" {
+			continue
+		}
+		name, err := json.Marshal(v.Name)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(v.Value)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s", name, value))
+	}
+	_, err := fmt.Fprintf(w, "{\n%s\n}\n", strings.Join(lines, ",\n"))
+	return err
+}
+
+type yamlWriter struct{}
+
+func (yamlWriter) Extension() string {
+	return ".yaml"
+}
+
+func (yamlWriter) Write(w io.Writer, vars []ConfigVar) error {
+	items := yaml.MapSlice{}
+	for _, v := range vars {
+		if v.Name == "" {
+			continue
+		}
+		items = append(items, yaml.MapItem{Key: v.Name, Value: v.Value})
+	}
+	data, err := yaml.Marshal(items)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// githubEnvWriter renders variables for $GITHUB_ENV or $GITHUB_OUTPUT files
+// See https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions#multiline-strings
+type githubEnvWriter struct{}
+
+func (githubEnvWriter) Extension() string {
+	return ".github.env"
+}
+
+func (githubEnvWriter) Write(w io.Writer, vars []ConfigVar) error {
+	for _, v := range vars {
+		if v.Name == "" {
+			continue
+		}
+		var err error
+		if strings.Contains(v.Value, "\n") {
+			delimiter := "CIUX_EOF"
+			for strings.Contains(v.Value, delimiter) {
+				delimiter += "_"
+			}
+			_, err = fmt.Fprintf(w, "%s<<%s\n%s\n%s\n", v.Name, delimiter, v.Value, delimiter)
+		} else {
+			_, err = fmt.Fprintf(w, "%s=%s\n", v.Name, v.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gitlabDotenvWriter renders variables for GitLab dotenv report artifacts
+// which support neither comments nor multiline values
+// See https://docs.gitlab.com/ee/ci/yaml/artifacts_reports.html#artifactsreportsdotenv
+type gitlabDotenvWriter struct{}
+
+func (gitlabDotenvWriter) Extension() string {
+	return ".gitlab.env"
+}
+
+func (gitlabDotenvWriter) Write(w io.Writer, vars []ConfigVar) error {
+	for _, v := range vars {
+		if v.Name == "" {
+			continue
+		}
+		if strings.ContainsAny(v.Value, "\r\n") {
+			return fmt.Errorf("multiline value for variable %s is not supported by gitlab dotenv format", v.Name)
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", v.Name, v.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type makeWriter struct{}
+
+func (makeWriter) Extension() string {
+	return ".mk"
+}
+
+func (makeWriter) Write(w io.Writer, vars []ConfigVar) error {
+	var replacer = strings.NewReplacer("$", "$$", "#", `\#`)
+	for _, v := range vars {
+		if v.Comment != "" {
+			if _, err := fmt.Fprintf(w, "# %s\n", v.Comment); err != nil {
+				return err
+			}
+		}
+		if v.Name == "" {
+			continue
+		}
+		if strings.ContainsAny(v.Value, "\r\n") {
+			return fmt.Errorf("multiline value for variable %s is not supported by make format", v.Name)
+		}
+		if _, err := fmt.Fprintf(w, "export %s := %s\n", v.Name, replacer.Replace(v.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadConfigFile reads the variables of a CIUXCONFIG file written with any supported format,
+// except make format
+func ReadConfigFile(ciuxConfigFilepath string) (map[string]string, error) {
+	data, err := os.ReadFile(ciuxConfigFilepath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read configuration file %s: %v", ciuxConfigFilepath, err)
+	}
+	vars := map[string]string{}
+	switch filepath.Ext(ciuxConfigFilepath) {
+	case ".json", ".yaml":
+		err = yaml.Unmarshal(data, &vars)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse configuration file %s: %v", ciuxConfigFilepath, err)
+		}
+		return vars, nil
+	case ".mk":
+		return nil, fmt.Errorf("unable to parse configuration file %s: make format is not supported", ciuxConfigFilepath)
+	}
+
+	// github-env and gitlab-dotenv values are not quoted
+	rawValues := strings.HasSuffix(ciuxConfigFilepath, githubEnvWriter{}.Extension()) ||
+		strings.HasSuffix(ciuxConfigFilepath, gitlabDotenvWriter{}.Extension())
+
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		varName, value, found := strings.Cut(line, "=")
+		if !found {
+			// github-env multiline value
+			varName, delimiter, found := strings.Cut(line, "<<")
+			if !found {
+				return nil, fmt.Errorf("unable to parse line %d of configuration file %s", i+1, ciuxConfigFilepath)
+			}
+			valueLines := []string{}
+			for i++; i < len(lines) && lines[i] != delimiter; i++ {
+				valueLines = append(valueLines, lines[i])
+			}
+			vars[varName] = strings.Join(valueLines, "\n")
+			continue
+		}
+		if rawValues {
+			vars[strings.TrimSpace(varName)] = value
+			continue
+		}
+		vars[strings.TrimSpace(varName)], err = unquoteValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse line %d of configuration file %s: %v", i+1, ciuxConfigFilepath, err)
+		}
+	}
+	return vars, nil
+}
+
+// unquoteValue removes shell or dotenv quoting from a value
+func unquoteValue(value string) (string, error) {
+	var sb strings.Builder
+	var quote rune
+	escaped := false
+	for _, c := range value {
+		switch {
+		case escaped:
+			if quote == '"' && c == 'n' {
+				c = '\n'
+			}
+			sb.WriteRune(c)
+			escaped = false
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				sb.WriteRune(c)
+			}
+		case c == '\\':
+			escaped = true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				sb.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	if quote != 0 || escaped {
+		return "", fmt.Errorf("unterminated quoted value %s", value)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigWriters(t *testing.T) {
+	require := require.New(t)
+
+	vars := []ConfigVar{
+		{Comment: "Label selector: build"},
+		{Name: "FINK_BROKER_DIR", Value: "/home/user/src/fink-broker"},
+		{Name: "CIUX_BUILD", Value: "true", Comment: "True if CIUX_IMAGE_URL need to be built"},
+		{Name: "CIUX_MESSAGE", Value: "it's $HOME #1"},
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: "bash",
+			expected: `# Label selector: build
+export FINK_BROKER_DIR=/home/user/src/fink-broker
+# True if CIUX_IMAGE_URL need to be built
+export CIUX_BUILD=true
+export CIUX_MESSAGE='it'\''s $HOME #1'
+`,
+		},
+		{
+			format: "dotenv",
+			expected: `# Label selector: build
+FINK_BROKER_DIR=/home/user/src/fink-broker
+# True if CIUX_IMAGE_URL need to be built
+CIUX_BUILD=true
+CIUX_MESSAGE="it's \$HOME #1"
+`,
+		},
+		{
+			format: "json",
+			expected: `{
+  "FINK_BROKER_DIR": "/home/user/src/fink-broker",
+  "CIUX_BUILD": "true",
+  "CIUX_MESSAGE": "it's $HOME #1"
+}
+`,
+		},
+		{
+			format: "yaml",
+			expected: `FINK_BROKER_DIR: /home/user/src/fink-broker
+CIUX_BUILD: "true"
+CIUX_MESSAGE: 'it''s $HOME #1'
+`,
+		},
+		{
+			format: "github-env",
+			expected: `FINK_BROKER_DIR=/home/user/src/fink-broker
+CIUX_BUILD=true
+CIUX_MESSAGE=it's $HOME #1
+`,
+		},
+		{
+			format: "gitlab-dotenv",
+			expected: `FINK_BROKER_DIR=/home/user/src/fink-broker
+CIUX_BUILD=true
+CIUX_MESSAGE=it's $HOME #1
+`,
+		},
+		{
+			format: "make",
+			expected: `# Label selector: build
+export FINK_BROKER_DIR := /home/user/src/fink-broker
+# True if CIUX_IMAGE_URL need to be built
+export CIUX_BUILD := true
+export CIUX_MESSAGE := it's $$HOME \#1
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			writer, err := GetConfigWriter(tt.format)
+			require.NoError(err)
+			var buf bytes.Buffer
+			err = writer.Write(&buf, vars)
+			require.NoError(err)
+			require.Equal(tt.expected, buf.String())
+		})
+	}
+
+	_, err := GetConfigWriter("fish")
+	require.Error(err)
+
+	writer, err := GetConfigWriter("")
+	require.NoError(err)
+	require.Equal(".sh", writer.Extension())
+}
+
+func TestConfigWritersMultiline(t *testing.T) {
+	require := require.New(t)
+
+	vars := []ConfigVar{
+		{Name: "CIUX_NOTES", Value: "line1\nline2"},
+	}
+
+	writer, err := GetConfigWriter("github-env")
+	require.NoError(err)
+	var buf bytes.Buffer
+	err = writer.Write(&buf, vars)
+	require.NoError(err)
+	require.Equal("CIUX_NOTES<<CIUX_EOF\nline1\nline2\nCIUX_EOF\n", buf.String())
+
+	writer, err = GetConfigWriter("dotenv")
+	require.NoError(err)
+	buf.Reset()
+	err = writer.Write(&buf, vars)
+	require.NoError(err)
+	require.Equal("CIUX_NOTES=\"line1\\nline2\"\n", buf.String())
+
+	for _, format := range []string{"gitlab-dotenv", "make"} {
+		writer, err = GetConfigWriter(format)
+		require.NoError(err)
+		err = writer.Write(&buf, vars)
+		require.Error(err, "format %s", format)
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := os.MkdirTemp("", "ciux-readconfigfile-test-")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	vars := []ConfigVar{
+		{Comment: "Label selector: build"},
+		{Name: "CIUX_IMAGE_URL", Value: "registry.io/project:v1.0.0"},
+		{Name: "CIUX_MESSAGE", Value: "it's $HOME #1"},
+		{Name: "CIUX_EMPTY", Value: ""},
+	}
+	expected := map[string]string{
+		"CIUX_IMAGE_URL": "registry.io/project:v1.0.0",
+		"CIUX_MESSAGE":   "it's $HOME #1",
+		"CIUX_EMPTY":     "",
+	}
+
+	for _, format := range ConfigFormats() {
+		writer, err := GetConfigWriter(format)
+		require.NoError(err)
+		ciuxConfigFilepath := filepath.Join(tmpDir, "ciux"+writer.Extension())
+		f, err := os.Create(ciuxConfigFilepath)
+		require.NoError(err)
+		err = writer.Write(f, vars)
+		require.NoError(err)
+		f.Close()
+
+		actual, err := ReadConfigFile(ciuxConfigFilepath)
+		if format == "make" {
+			require.Error(err)
+			continue
+		}
+		require.NoError(err, "format %s", format)
+		require.Equal(expected, actual, "format %s", format)
+	}
+}
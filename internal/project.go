@@ -1,14 +1,21 @@
 package internal
 
 import (
+	"debug/buildinfo"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/k8s-school/ciux/log"
 	"github.com/k8s-school/ciux/resources"
 	"k8s.io/apimachinery/pkg/labels"
@@ -26,6 +33,83 @@ type Project struct {
 	TemporaryRegistry string
 	Selector          labels.Selector
 	Config            ProjConfig
+	// Format of the CIUXCONFIG file, see ConfigFormats()
+	ConfigFormat string
+	// Behaviour when the registry can not be checked for an existing image, see RegistryErrorPolicies()
+	OnRegistryError RegistryErrorPolicy
+	// Mirrors for the images of the dependencies
+	Mirrors Mirrors
+	// Platforms which must all exist for the project image to be in the registry
+	Platforms []string
+	// Images found in the registry, but ignored because of missing platforms or outdated base images
+	IgnoredImages []Image
+	// Strategy used to compute the tag of the project image
+	ImageTagStrategy ImageTagStrategy
+	// Additional images, defined in the 'images' section of the .ciux file
+	Images []Image
+}
+
+// ImageTagStrategy is the way the tag of the project image is computed
+type ImageTagStrategy string
+
+const (
+	// ImageTagGitDescribe tags the image with the git version of the latest source code change,
+	// the registry is probed for each commit since this change
+	ImageTagGitDescribe ImageTagStrategy = "git-describe"
+	// ImageTagContentHash tags the image with a hash of the source pathes content,
+	// the registry is probed once and the tag is the same on all branches with the same source code
+	ImageTagContentHash ImageTagStrategy = "content-hash"
+	// ContentHashTagPrefix is the prefix of content-hash tags
+	ContentHashTagPrefix = "src-"
+	// contentHashTagLength is the number of hash characters in content-hash tags
+	contentHashTagLength = 16
+)
+
+// ImageTagStrategies returns the supported image tag strategies
+func ImageTagStrategies() []string {
+	return []string{string(ImageTagGitDescribe), string(ImageTagContentHash)}
+}
+
+// ParseImageTagStrategy returns the image tag strategy for a name, an empty name returns the git-describe strategy
+func ParseImageTagStrategy(strategy string) (ImageTagStrategy, error) {
+	if strategy == "" {
+		return ImageTagGitDescribe, nil
+	}
+	if !slices.Contains(ImageTagStrategies(), strategy) {
+		return "", fmt.Errorf("unsupported image tag strategy %q, use one of %s", strategy, strings.Join(ImageTagStrategies(), ", "))
+	}
+	return ImageTagStrategy(strategy), nil
+}
+
+// RegistryErrorPolicy is the behaviour of ciux when the registry returns an error
+// which is not "image not found" while looking for an existing image
+type RegistryErrorPolicy string
+
+const (
+	// RegistryErrorFail returns the registry error
+	RegistryErrorFail RegistryErrorPolicy = "fail"
+	// RegistryErrorRebuild considers the image is not in the registry
+	RegistryErrorRebuild RegistryErrorPolicy = "rebuild"
+	// RegistryErrorWarn considers the image is not in the registry and displays a warning
+	RegistryErrorWarn RegistryErrorPolicy = "warn"
+	// DefaultRegistryErrorPolicy keeps the image rebuild behaviour of previous ciux versions, with a warning
+	DefaultRegistryErrorPolicy = RegistryErrorWarn
+)
+
+// RegistryErrorPolicies returns the supported registry error policies
+func RegistryErrorPolicies() []string {
+	return []string{string(RegistryErrorFail), string(RegistryErrorRebuild), string(RegistryErrorWarn)}
+}
+
+// ParseRegistryErrorPolicy returns the registry error policy for a name, an empty name returns the default policy
+func ParseRegistryErrorPolicy(policy string) (RegistryErrorPolicy, error) {
+	if policy == "" {
+		return DefaultRegistryErrorPolicy, nil
+	}
+	if !slices.Contains(RegistryErrorPolicies(), policy) {
+		return "", fmt.Errorf("unsupported registry error policy %q, use one of %s", policy, strings.Join(RegistryErrorPolicies(), ", "))
+	}
+	return RegistryErrorPolicy(policy), nil
 }
 
 func NewCoreProject(repository_path string, forcedBranch string) (Project, ProjConfig, error) {
@@ -38,15 +122,65 @@ func NewCoreProject(repository_path string, forcedBranch string) (Project, ProjC
 		return Project{}, ProjConfig{}, fmt.Errorf("unable to read configuration file: %v", err)
 	}
 
-	for _, path := range config.SourcePathes {
-		if filepath.IsAbs(path) {
-			return Project{}, ProjConfig{}, fmt.Errorf("source path %s must be relative", path)
+	err = validateSourcePathes(config.SourcePathes)
+	if err != nil {
+		return Project{}, ProjConfig{}, err
+	}
+	for _, imageConfig := range config.Images {
+		if imageConfig.Name == "" && imageConfig.Suffix == "" {
+			return Project{}, ProjConfig{}, fmt.Errorf("image name or suffix is required in 'images' section")
 		}
-		if path != filepath.Clean(path) {
-			return Project{}, ProjConfig{}, fmt.Errorf("source path %s must be clean", path)
+		err = validateSourcePathes(imageConfig.SourcePathes)
+		if err != nil {
+			return Project{}, ProjConfig{}, err
+		}
+	}
+
+	for _, platform := range config.Platforms {
+		if _, err := v1.ParsePlatform(platform); err != nil {
+			return Project{}, ProjConfig{}, fmt.Errorf("invalid platform %s: %v", platform, err)
+		}
+	}
+
+	err = validateBranchStrategy(config.BranchStrategy)
+	if err != nil {
+		return Project{}, ProjConfig{}, err
+	}
+	for _, depConfig := range config.Dependencies {
+		err = validateBranchStrategy(depConfig.BranchStrategy)
+		if err != nil {
+			return Project{}, ProjConfig{}, fmt.Errorf("dependency %s: %w", depConfig.Url, err)
 		}
+		err = depConfig.validateRef()
+		if err != nil {
+			return Project{}, ProjConfig{}, err
+		}
+	}
+
+	imageTagStrategy, err := ParseImageTagStrategy(config.ImageTagStrategy)
+	if err != nil {
+		return Project{}, ProjConfig{}, err
 	}
 
+	if config.AutoSourcePathes != "" && config.AutoSourcePathes != AutoSourcePathesDockerfile {
+		return Project{}, ProjConfig{}, fmt.Errorf("invalid autoSourcePathes %s, only %s is supported", config.AutoSourcePathes, AutoSourcePathesDockerfile)
+	}
+
+	sourcePathes := config.SourcePathes
+	if config.Dockerignore || config.AutoSourcePathes != "" {
+		root, err := git.GetRoot()
+		if err != nil {
+			return Project{}, ProjConfig{}, fmt.Errorf("unable to get root of project repository: %v", err)
+		}
+		sourcePathes, err = config.resolveSourcePathes(root, sourcePathes, config.GetDockerfile())
+		if err != nil {
+			return Project{}, ProjConfig{}, err
+		}
+		slog.Debug("Resolved source pathes", "sourcePathes", sourcePathes)
+	}
+
+	SetRegistryConfigs(config.Registries)
+
 	req, err := labels.NewRequirement("project", selection.Equals, []string{"core"})
 	if err != nil {
 		return Project{}, ProjConfig{}, fmt.Errorf("unable to create label requirement: %v", err)
@@ -54,15 +188,59 @@ func NewCoreProject(repository_path string, forcedBranch string) (Project, ProjC
 
 	p := Project{
 		GitMain:       git,
-		SourcePathes:  config.SourcePathes,
+		SourcePathes:  sourcePathes,
 		ImageRegistry: config.Registry,
 		ForcedBranch:  forcedBranch,
 		Selector:      labels.NewSelector().Add(*req),
 		Config:        config,
+		Mirrors:       config.Mirrors,
+		Platforms:     config.Platforms,
+
+		ImageTagStrategy: imageTagStrategy,
 	}
 	return p, config, nil
 }
 
+// validateSourcePathes checks that source pathes are relative and clean, or valid patterns
+func validateSourcePathes(sourcePathes []string) error {
+	for _, path := range sourcePathes {
+		if isSourcePattern(path) {
+			if _, err := newSourceRule(path); err != nil {
+				return err
+			}
+			continue
+		}
+		if filepath.IsAbs(path) {
+			return fmt.Errorf("source path %s must be relative", path)
+		}
+		if path != filepath.Clean(path) {
+			return fmt.Errorf("source path %s must be clean", path)
+		}
+	}
+	return nil
+}
+
+// AutoSourcePathesDockerfile is the autoSourcePathes mode which computes the source pathes from the COPY and ADD instructions of the Dockerfile
+const AutoSourcePathesDockerfile = "Dockerfile"
+
+// resolveSourcePathes returns the source pathes of an image built with a Dockerfile:
+// the Dockerfile and its COPY and ADD sources followed by sourcePathes if autoSourcePathes is set,
+// then the .dockerignore patterns of the Dockerfile if enabled
+func (c ProjConfig) resolveSourcePathes(root string, sourcePathes []string, dockerfile string) ([]string, error) {
+	resolved := sourcePathes
+	if c.AutoSourcePathes == AutoSourcePathesDockerfile {
+		dockerfilePathes, err := DockerfileSourcePathes(root, dockerfile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compute source pathes from Dockerfile: %w", err)
+		}
+		resolved = append(dockerfilePathes, sourcePathes...)
+	}
+	if c.Dockerignore || c.AutoSourcePathes == AutoSourcePathesDockerfile {
+		return WithDockerignore(root, resolved, dockerfile)
+	}
+	return resolved, nil
+}
+
 // NewProject creates a new Project struct
 // It reads the repository_path/.ciux.yaml configuration file
 // and retrieve the work branch for all dependencies
@@ -102,6 +280,10 @@ func NewProject(repository_path string, forcedBranch string, mainProjectOnly boo
 					Git: &Git{
 						Url: depConfig.Url,
 					},
+					BranchStrategy: depConfig.BranchStrategy,
+					Ref:            depConfig.Ref,
+					Commit:         depConfig.Commit,
+					Version:        depConfig.Version,
 				}
 			}
 			if selectors.Matches(depConfig.Labels) {
@@ -111,11 +293,95 @@ func NewProject(repository_path string, forcedBranch string, mainProjectOnly boo
 		}
 
 		p.Dependencies = deps
+
+		if config.CheckBaseImages && selectors.Matches(labels.Set{BuildLabel: "true"}) {
+			err = p.addBaseImages()
+			if err != nil {
+				return Project{}, err
+			}
+		}
+	}
+	err = p.scanRemoteDeps()
+	if err != nil {
+		return Project{}, err
 	}
-	p.scanRemoteDeps()
 	return p, nil
 }
 
+// BuildLabel is the label of the dependencies required to build the project image,
+// the base images of the project Dockerfiles are checked if the label selector matches build=true
+const BuildLabel = "build"
+
+// addBaseImages adds the base images of the project Dockerfiles to the dependencies
+func (p *Project) addBaseImages() error {
+	root, err := p.GitMain.GetRoot()
+	if err != nil {
+		return fmt.Errorf("unable to get root of project repository: %v", err)
+	}
+	baseImages, err := GetBaseImages(root, p.SourcePathes)
+	if err != nil {
+		return err
+	}
+	for i, baseImage := range baseImages {
+		found := false
+		for _, dep := range p.Dependencies {
+			if dep.Image == baseImage.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			slog.Debug("Base image selected", "image", baseImage)
+			p.Dependencies = append(p.Dependencies, &Dependency{Image: baseImage.Name, BaseImage: &baseImages[i]})
+		}
+	}
+	return nil
+}
+
+// CheckBaseImages checks that the base images of the project Dockerfiles exist,
+// with their --platform if any, it is done before retrieving the dependencies so that the build fails early
+func (p *Project) CheckBaseImages() ([]name.Reference, error) {
+	foundImages := []name.Reference{}
+	for _, dep := range p.Dependencies {
+		if dep.BaseImage == nil {
+			continue
+		}
+		ref, err := p.checkBaseImage(dep)
+		if err != nil {
+			return foundImages, err
+		}
+		foundImages = append(foundImages, ref)
+	}
+	return foundImages, nil
+}
+
+func (p *Project) checkBaseImage(dep *Dependency) (name.Reference, error) {
+	imageUrl, err := dep.GetImageName(p.ImageRegistry, p.Mirrors)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image name for base image %s: %v", dep.BaseImage, err)
+	}
+	slog.Debug("Check base image existence", "image", imageUrl)
+	ref, err := ParseReference(imageUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base image %s: %v", dep.BaseImage, err)
+	}
+	dep.ImageDigest, err = GetImageDigest(ref)
+	if err != nil {
+		return nil, fmt.Errorf("base image %s is not available: %w", dep.BaseImage, err)
+	}
+	if dep.BaseImage.Platform != "" {
+		missing, err := MissingPlatforms(ref, []string{dep.BaseImage.Platform})
+		if err != nil {
+			return nil, fmt.Errorf("unable to check platform of base image %s: %w", dep.BaseImage, err)
+		}
+		if len(missing) != 0 {
+			return nil, fmt.Errorf("base image %s is not available for platform %s", dep.BaseImage, dep.BaseImage.Platform)
+		}
+	}
+	dep.baseImageRef = ref
+	return ref, nil
+}
+
 // GetName returns the project name from config if available, otherwise from directory name
 func (p *Project) GetName() (string, error) {
 	if p.Config.Project != "" {
@@ -145,6 +411,8 @@ func (p *Project) String() string {
 
 			if dep.Package != "" {
 				msg += fmt.Sprintf("\n  Package: %s", dep.Package)
+			} else if dep.BaseImage != nil {
+				msg += fmt.Sprintf("\n  Image: %s base-image=%s:%d", dep.Image, dep.BaseImage.Dockerfile, dep.BaseImage.Line)
 			} else if dep.Image != "" {
 				msg += fmt.Sprintf("\n  Image: %s", dep.Image)
 			} else if dep.Git != nil {
@@ -163,6 +431,9 @@ func (p *Project) String() string {
 				} else {
 					msg += fmt.Sprintf("\n  %s remote-only=true branch=%s commit=%s", dep.Git.Url, dep.Git.WorkBranch, dep.Git.RemoteHash)
 				}
+				if dep.Git.Ref != "" {
+					msg += fmt.Sprintf(" ref=%s", dep.Git.Ref)
+				}
 				if dep.Pull {
 					msg += " pull=true"
 				}
@@ -202,20 +473,44 @@ func (p *Project) CheckDepImages() ([]name.Reference, error) {
 	foundImages := []name.Reference{}
 	for i, dep := range p.Dependencies {
 		if dep.Pull {
-			imageUrl, err := dep.GetImageName(p.ImageRegistry)
+			imageUrl, err := dep.GetImageName(p.ImageRegistry, p.Mirrors)
 			if err != nil {
 				return foundImages, fmt.Errorf("unable to get image name for git repository %s: %v", p.Dependencies[i].Git.Url, err)
 			}
 			slog.Debug("Check image existence", "image", imageUrl)
-			_, ref, err := DescImage(imageUrl)
+			ref, err := ParseReference(imageUrl)
 			if err != nil {
-				return foundImages, fmt.Errorf("unable to check image existence: %v, %v", err, ref)
+				return foundImages, fmt.Errorf("parsing reference %q: %w", imageUrl, err)
+			}
+			// The digest request also checks the image existence
+			dep.ImageDigest, err = GetImageDigest(ref)
+			if err != nil {
+				return foundImages, fmt.Errorf("unable to check image existence: %w", err)
+			}
+			foundImages = append(foundImages, ref)
+		} else if dep.BaseImage != nil {
+			// Base images are usually already checked by CheckBaseImages
+			ref := dep.baseImageRef
+			if ref == nil {
+				var err error
+				ref, err = p.checkBaseImage(dep)
+				if err != nil {
+					return foundImages, err
+				}
 			}
 			foundImages = append(foundImages, ref)
 		} else if dep.Image != "" {
-			_, ref, err := DescImage(dep.Image)
+			imageUrl, err := dep.GetImageName(p.ImageRegistry, p.Mirrors)
+			if err != nil {
+				return foundImages, fmt.Errorf("unable to get image name for %s: %v", dep.Image, err)
+			}
+			ref, err := ParseReference(imageUrl)
 			if err != nil {
-				return foundImages, fmt.Errorf("unable to check image existence: %v, %v", err, ref)
+				return foundImages, fmt.Errorf("parsing reference %q: %w", imageUrl, err)
+			}
+			dep.ImageDigest, err = GetImageDigest(ref)
+			if err != nil {
+				return foundImages, fmt.Errorf("unable to check image existence: %w", err)
 			}
 			foundImages = append(foundImages, ref)
 		}
@@ -227,13 +522,30 @@ func (p *Project) InstallGoModules() (string, error) {
 	msg := ""
 	for _, dep := range p.Dependencies {
 		if dep.Package != "" {
-			cmd := fmt.Sprintf("go install %s", dep.Package)
+			pkg := dep.Package
+			if dep.PackageVersion != "" {
+				// Replay installs the recorded version
+				pkgPath, _, _ := strings.Cut(dep.Package, "@")
+				pkg = pkgPath + "@" + dep.PackageVersion
+			}
+			cmd := fmt.Sprintf("go install %s", pkg)
 			outstr, errstr, err := ExecCmd(cmd, false)
 			slog.Debug("Install package", "cmd", cmd, "out", outstr, "err", errstr)
 			if err != nil {
-				return msg, fmt.Errorf("unable to install go module %s: %v", dep.Package, err)
+				return msg, fmt.Errorf("unable to install go module %s: %v", pkg, err)
+			}
+			version, err := getInstalledGoPackageVersion(pkg)
+			if err != nil {
+				// e.g. the binary is installed in a GOOS_GOARCH sub-directory of GOBIN when cross-compiling
+				slog.Warn("Unable to read version of installed go module, it is not recorded", "package", pkg, "error", err)
+				version = ""
 			}
-			msg += fmt.Sprintf("  %s\n", dep.Package)
+			if dep.PackageVersion == "" {
+				dep.PackageVersion = version
+			} else if version != "" && version != dep.PackageVersion {
+				return msg, fmt.Errorf("go module %s is installed at version %s, expected %s", pkg, version, dep.PackageVersion)
+			}
+			msg += fmt.Sprintf("  %s version=%s\n", dep.Package, dep.PackageVersion)
 		} else if dep.Clone {
 			isGoMod, err := dep.Git.IsGoModule()
 			if err != nil {
@@ -251,9 +563,50 @@ func (p *Project) InstallGoModules() (string, error) {
 	return msg, nil
 }
 
+// getInstalledGoPackageVersion returns the module version of a Go package installed by 'go install',
+// read from the build information of its binary, it is empty for a package built from a local module
+func getInstalledGoPackageVersion(pkg string) (string, error) {
+	out, _, err := ExecCmd("go env GOBIN GOPATH", false)
+	if err != nil {
+		return "", err
+	}
+	gobin, gopath, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	if gobin == "" {
+		gopath, _, _ = strings.Cut(strings.TrimSpace(gopath), string(os.PathListSeparator))
+		gobin = filepath.Join(gopath, "bin")
+	}
+	pkgPath, _, _ := strings.Cut(pkg, "@")
+	return readGoBinaryVersion(filepath.Join(gobin, goBinaryName(pkgPath)))
+}
+
+// goBinaryName returns the name of the binary installed by 'go install' for a package path,
+// i.e. its last element, or the previous one for a major version suffix like /v2
+func goBinaryName(pkgPath string) string {
+	name := path.Base(pkgPath)
+	if majorVersionRegexp.MatchString(name) && path.Dir(pkgPath) != "." {
+		name = path.Base(path.Dir(pkgPath))
+	}
+	return name
+}
+
+var majorVersionRegexp = regexp.MustCompile(`^v[0-9]+$`)
+
+// readGoBinaryVersion returns the version of the main module of a Go binary, empty for a development build
+func readGoBinaryVersion(binaryPath string) (string, error) {
+	info, err := buildinfo.ReadFile(binaryPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to read build information of %s: %v", binaryPath, err)
+	}
+	if info.Main.Version == "(devel)" {
+		return "", nil
+	}
+	return info.Main.Version, nil
+}
+
 // scanRemoteDeps retrieves the work branch for each dependency
-// It is the same branch as the main repository if it exists
-// or the default branch of the dependency repository otherwise
+// It is the first branch of the dependency branch strategy which exists in the dependency repository,
+// by default the same branch as the main repository if it exists, or the default branch of the dependency repository otherwise
+// Dependencies pinned to a ref, a commit or a version range are resolved to a commit
 func (project *Project) scanRemoteDeps() error {
 
 	var err error
@@ -267,29 +620,24 @@ func (project *Project) scanRemoteDeps() error {
 		project.GitMain.WorkBranch = project.ForcedBranch
 	}
 
-	var hash string
+	data := project.getBranchStrategyData()
 	for _, dep := range project.Dependencies {
 		if dep.Git != nil {
 			err = dep.Git.LsRemote()
 			if err != nil {
 				return fmt.Errorf("unable to ls-remote for dependency repository %s: %v", dep.Git.Url, err)
 			}
-			var hasBranch bool
-			hasBranch, hash, err = dep.Git.HasBranch(project.GitMain.WorkBranch)
-			if err != nil {
-				return fmt.Errorf("unable to check branch existence for dependency repository %s: %v", dep.Git.Url, err)
-			}
-			if hasBranch {
-				dep.Git.WorkBranch = project.GitMain.WorkBranch
-			} else {
-				var main string
-				main, hash, err = dep.Git.MainBranch()
+			if dep.IsPinned() {
+				err = dep.resolveRef()
 				if err != nil {
-					return fmt.Errorf("unable to get main branch for project repository %s: %v", project.GitMain.Url, err)
+					return fmt.Errorf("unable to resolve ref for dependency repository %s: %v", dep.Git.Url, err)
 				}
-				dep.Git.WorkBranch = main
+				continue
+			}
+			dep.Git.WorkBranch, dep.Git.RemoteHash, err = dep.Git.ResolveBranch(project.getBranchStrategy(dep.BranchStrategy), data)
+			if err != nil {
+				return fmt.Errorf("unable to get work branch for dependency repository %s: %v", dep.Git.Url, err)
 			}
-			dep.Git.RemoteHash = hash
 		}
 	}
 	if log.IsDebugEnabled() {
@@ -338,12 +686,47 @@ func (p *Project) GetCiuxConfigFilepath() (string, error) {
 		if err != nil {
 			return "", fmt.Errorf("unable to get ciux config directory: %v", err)
 		}
-		ciuxFileName := "ciux" + LabelSelectorToFileName(p.Selector) + ".sh"
+		writer, err := GetConfigWriter(p.ConfigFormat)
+		if err != nil {
+			return "", err
+		}
+		ciuxFileName := "ciux" + LabelSelectorToFileName(p.Selector) + writer.Extension()
 		ciuxConfigFile = filepath.Join(ciuxCfgDir, ciuxFileName)
 	}
 	return ciuxConfigFile, nil
 }
 
+// getImageDepConfigVars returns the variables of the CIUXCONFIG file for an image dependency
+// <NAME>_IMAGE is the image to pull, i.e. its mirror if any, and <NAME>_IMAGE_CANONICAL the image of the configuration
+func (p *Project) getImageDepConfigVars(dep *Dependency) ([]ConfigVar, error) {
+	varName, err := GetImageEnVarPrefix(dep.Image)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get environment variable name for image %s: %v", dep.Image, err)
+	}
+	image, err := dep.GetImageName(p.ImageRegistry, p.Mirrors)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image name for %s: %v", dep.Image, err)
+	}
+	vars := []ConfigVar{{Name: varName + "_IMAGE", Value: image}}
+	if dep.BaseImage != nil {
+		vars[0].Comment = fmt.Sprintf("Base image of %s", dep.BaseImage.Dockerfile)
+	}
+	if image != dep.Image {
+		vars = append(vars, ConfigVar{Name: varName + "_IMAGE_CANONICAL", Value: dep.Image})
+	}
+	if dep.ImageDigest != "" {
+		pinned, err := PinImage(image, dep.ImageDigest)
+		if err != nil {
+			return nil, err
+		}
+		vars = append(vars,
+			ConfigVar{Name: varName + "_IMAGE_DIGEST", Value: dep.ImageDigest},
+			ConfigVar{Name: varName + "_IMAGE_PINNED", Value: pinned},
+		)
+	}
+	return vars, nil
+}
+
 func (p *Project) GetRepositoryPath() (string, error) {
 	repositoryPath, err := p.GitMain.GetRoot()
 	if err != nil {
@@ -352,151 +735,139 @@ func (p *Project) GetRepositoryPath() (string, error) {
 	return repositoryPath, nil
 }
 
-// WriteOutConfig writes out the shell configuration file
-// used be the CI/CD pipeline
-func (p *Project) WriteOutConfig() (string, error) {
-
-	p.InitCiuxConfigDir()
-
-	ciuxConfigFilepath, err := p.GetCiuxConfigFilepath()
-	if err != nil {
-		return "", fmt.Errorf("unable to get ciux config file path: %v", err)
-	}
-
-	f, err := os.Create(ciuxConfigFilepath)
-	if err != nil {
-		return "", fmt.Errorf("unable to create configuration file %s: %v", ciuxConfigFilepath, err)
-	}
-	defer f.Close()
+// GetConfigVars returns the variables of the CIUXCONFIG file
+// used by the CI/CD pipeline
+func (p *Project) GetConfigVars() ([]ConfigVar, error) {
 
 	gitDeps := []*Git{}
-	imageDeps := []string{}
+	imageDeps := []*Dependency{}
 	for _, dep := range p.Dependencies {
 		if dep.Git != nil {
 			gitDeps = append(gitDeps, dep.Git)
 		} else if dep.Image != "" {
-			imageDeps = append(imageDeps, dep.Image)
+			imageDeps = append(imageDeps, dep)
 		}
 	}
 
-	labels := fmt.Sprintf("# Label selector: %s\n", p.Selector)
-	f.WriteString(labels)
+	vars := []ConfigVar{
+		{Comment: fmt.Sprintf("Label selector: %s", p.Selector)},
+	}
 
 	gitRepos := append(gitDeps, p.GitMain)
 	for _, gitObj := range gitRepos {
 		varName, err := gitObj.GetEnVarPrefix()
 		if err != nil {
-			return "", fmt.Errorf("unable to get environment variable name for git repository %v: %v", gitObj, err)
+			return nil, fmt.Errorf("unable to get environment variable name for git repository %v: %v", gitObj, err)
 		}
 		if !gitObj.isRemoteOnly() {
 			root, err := gitObj.GetRoot()
 			if err != nil {
-				return "", fmt.Errorf("unable to get root of git repository: %v", err)
-			}
-
-			depEnv := fmt.Sprintf("export %s_DIR=%s\n", varName, root)
-			_, err = f.WriteString(depEnv)
-			if err != nil {
-				return "", fmt.Errorf("unable to write variable %s to file %s: %v", varName, ciuxConfigFilepath, err)
+				return nil, fmt.Errorf("unable to get root of git repository: %v", err)
 			}
-
 			rev, err := gitObj.GetHeadRevision()
 			if err != nil {
-				return "", fmt.Errorf("unable to describe git repository: %v", err)
+				return nil, fmt.Errorf("unable to describe git repository: %v", err)
 			}
-			depVersion := fmt.Sprintf("export %s_VERSION=%s\n", varName, rev.GetVersion())
-			_, err = f.WriteString(depVersion)
-			if err != nil {
-				return "", fmt.Errorf("unable to write variable %s_VERSION to file %s: %v", varName, ciuxConfigFilepath, err)
+			vars = append(vars,
+				ConfigVar{Name: varName + "_DIR", Value: root},
+				ConfigVar{Name: varName + "_VERSION", Value: rev.GetVersion()},
+			)
+		}
+		vars = append(vars, ConfigVar{Name: varName + "_WORKBRANCH", Value: gitObj.WorkBranch})
+		if gitObj.Ref != "" {
+			comment := ""
+			if gitObj.VersionRange != "" {
+				comment = fmt.Sprintf("Highest tag matching version %s", gitObj.VersionRange)
 			}
+			commit := gitObj.RemoteHash
+			if !gitObj.isRemoteOnly() {
+				rev, err := gitObj.GetHeadRevision()
+				if err != nil {
+					return nil, fmt.Errorf("unable to describe git repository: %v", err)
+				}
+				commit = rev.Hash
+			}
+			vars = append(vars,
+				ConfigVar{Name: varName + "_REF", Value: gitObj.Ref, Comment: comment},
+				ConfigVar{Name: varName + "_COMMIT", Value: commit},
+			)
 		}
+	}
 
-		depEnv := fmt.Sprintf("export %s_WORKBRANCH=%s\n", varName, gitObj.WorkBranch)
-		_, err = f.WriteString(depEnv)
+	for _, dep := range imageDeps {
+		imageVars, err := p.getImageDepConfigVars(dep)
 		if err != nil {
-			return "", fmt.Errorf("unable to write variable %s to file %s: %v", varName, ciuxConfigFilepath, err)
+			return nil, err
 		}
+		vars = append(vars, imageVars...)
 	}
 
-	for _, image := range imageDeps {
-		varName, err := GetImageEnVarPrefix(image)
-		if err != nil {
-			return "", fmt.Errorf("unable to get environment variable name for image %s: %v", image, err)
-		}
-		imageEnv := fmt.Sprintf("export %s_IMAGE=%s\n", varName, image)
-		_, err = f.WriteString(imageEnv)
-		if err != nil {
-			return "", fmt.Errorf("unable to write variable %s_IMAGE to file %s: %v", varName, ciuxConfigFilepath, err)
-		}
+	if p.Config.CheckBaseImages {
+		vars = append(vars, ConfigVar{Name: "CIUX_BASE_IMAGES", Value: FormatBaseImagesLabel(p.GetBaseImageDigests()), Comment: fmt.Sprintf("Value of the %s label of CIUX_IMAGE_URL", BaseImagesLabel)})
 	}
 
 	// Image containing the latest code changes
-	imageRegistry := p.ImageRegistry
-	imageEnv := fmt.Sprintf("export CIUX_IMAGE_REGISTRY=%s\n", imageRegistry)
-	_, err = f.WriteString(imageEnv)
-	if err != nil {
-		return "", fmt.Errorf("unable to write variable CIUX_IMAGE_REGISTRY to file %s: %v", ciuxConfigFilepath, err)
-	}
-	imageEnv = fmt.Sprintf("export CIUX_IMAGE_NAME=%s\n", p.Image.Name)
-	_, err = f.WriteString(imageEnv)
-	if err != nil {
-		return "", fmt.Errorf("unable to write variable CIUX_IMAGE_NAME to file %s: %v", ciuxConfigFilepath, err)
-	}
-	imageTag := p.Image.Tag
 	prefix, err := p.GitMain.GetEnVarPrefix()
 	if err != nil {
-		return "", fmt.Errorf("unable to get environment variable prefix for project main git repository: %v", err)
+		return nil, fmt.Errorf("unable to get environment variable prefix for project main git repository: %v", err)
 	}
-	imageEnv = fmt.Sprintf("# Image which contains latest code source changes %s_VERSION\n", prefix)
-	imageEnv += fmt.Sprintf("export CIUX_IMAGE_TAG=%s\n", imageTag)
-	_, err = f.WriteString(imageEnv)
+	vars = append(vars,
+		ConfigVar{Name: "CIUX_IMAGE_REGISTRY", Value: p.ImageRegistry},
+		ConfigVar{Name: "CIUX_IMAGE_NAME", Value: p.Image.Name},
+		ConfigVar{Name: "CIUX_IMAGE_TAG", Value: p.Image.Tag, Comment: fmt.Sprintf("Image which contains latest code source changes %s_VERSION", prefix)},
+		ConfigVar{Name: "CIUX_IMAGE_URL", Value: p.Image.Url()},
+		ConfigVar{Name: "CIUX_BUILD", Value: fmt.Sprintf("%t", !p.Image.InRegistry), Comment: "True if CIUX_IMAGE_URL need to be built"},
+		ConfigVar{Name: "CIUX_IMAGE_DIGEST", Value: p.Image.Digest, Comment: "Empty if CIUX_IMAGE_URL need to be built"},
+		ConfigVar{Name: "CIUX_IMAGE_PINNED", Value: p.Image.PinnedUrl()},
+	)
+
+	vars = append(vars, p.getImagesConfigVars()...)
+
+	promotedImage, err := p.GetPromotedImage()
 	if err != nil {
-		return "", fmt.Errorf("unable to write variable CIUX_IMAGE_TAG to file %s: %v", ciuxConfigFilepath, err)
+		return nil, err
 	}
+	vars = append(vars, ConfigVar{Name: "CIUX_PROMOTED_IMAGE_URL", Value: promotedImage.Url(), Comment: "Promoted image is the image which will be push if CI run successfully"})
 
-	imageUrl := fmt.Sprintf("export CIUX_IMAGE_URL=%s\n", p.Image.Url())
-	_, err = f.WriteString(imageUrl)
-	if err != nil {
-		return "", fmt.Errorf("unable to write variable CIUX_IMAGE_URL to file %s: %v", ciuxConfigFilepath, err)
+	// Temporary image
+	if p.TemporaryRegistry != "" {
+		temporaryImage := promotedImage
+		temporaryImage.Registry = p.TemporaryRegistry
+		vars = append(vars, ConfigVar{Name: "CIUX_TEMPORARY_IMAGE_URL", Value: temporaryImage.Url(), Comment: "Temporary image is the image which will be push if CI run successfully"})
 	}
-	notInRegistry := "# True if CIUX_IMAGE_URL need to be built\n"
-	notInRegistry += fmt.Sprintf("export CIUX_BUILD=%t\n", !p.Image.InRegistry)
-	_, err = f.WriteString(notInRegistry)
+	return vars, nil
+}
+
+// WriteOutConfig writes out the configuration file
+// used be the CI/CD pipeline, in the format of the project
+func (p *Project) WriteOutConfig() (string, error) {
+
+	p.InitCiuxConfigDir()
+
+	writer, err := GetConfigWriter(p.ConfigFormat)
 	if err != nil {
-		return "", fmt.Errorf("unable to write variable CIUX_BUILD to file %s: %v", ciuxConfigFilepath, err)
+		return "", err
 	}
 
-	rev, err := p.GitMain.GetHeadRevision()
+	vars, err := p.GetConfigVars()
 	if err != nil {
-		return "", fmt.Errorf("unable to describe git repository: %v", err)
+		return "", err
 	}
 
-	// Promoted image
-	promotedImage := Image{
-		Registry: p.ImageRegistry,
-		Name:     p.Image.Name,
-		Tag:      rev.GetVersion(),
+	ciuxConfigFilepath, err := p.GetCiuxConfigFilepath()
+	if err != nil {
+		return "", fmt.Errorf("unable to get ciux config file path: %v", err)
 	}
-	promotedImageUrl := "# Promoted image is the image which will be push if CI run successfully\n"
-	promotedImageUrl += fmt.Sprintf("export CIUX_PROMOTED_IMAGE_URL=%s", promotedImage.Url())
-	_, err = f.WriteString(promotedImageUrl)
+
+	f, err := os.Create(ciuxConfigFilepath)
 	if err != nil {
-		return "", fmt.Errorf("unable to write variable CIUX_IMAGE_URL to file %s: %v", ciuxConfigFilepath, err)
+		return "", fmt.Errorf("unable to create configuration file %s: %v", ciuxConfigFilepath, err)
 	}
+	defer f.Close()
 
-	// Temporary image
-	if p.TemporaryRegistry != "" {
-		temporaryImage := Image{
-			Registry: p.TemporaryRegistry,
-			Name:     p.Image.Name,
-			Tag:      rev.GetVersion(),
-		}
-		temporaryImageUrl := "# Temporary image is the image which will be push if CI run successfully\n"
-		temporaryImageUrl += fmt.Sprintf("export CIUX_TEMPORARY_IMAGE_URL=%s", temporaryImage.Url())
-		_, err = f.WriteString(temporaryImageUrl)
-		if err != nil {
-			return "", fmt.Errorf("unable to write variable CIUX_TEMPORARY_IMAGE_URL to file %s: %v", ciuxConfigFilepath, err)
-		}
+	err = writer.Write(f, vars)
+	if err != nil {
+		return "", fmt.Errorf("unable to write configuration file %s: %v", ciuxConfigFilepath, err)
 	}
 
 	msg := fmt.Sprintf("Configuration file:\n  %s", ciuxConfigFilepath)
@@ -519,15 +890,37 @@ func (p *Project) GetGits() []*Git {
 //	a suffix can be added to image name
 //	image existence in the registry can be checked
 func (project *Project) GetImageName(suffix string, checkRegistry bool) error {
+	project.IgnoredImages = nil
+	imageName, err := project.GetImageBaseName(suffix)
+	if err != nil {
+		return err
+	}
+	return project.computeImage(imageName, checkRegistry)
+}
+
+// computeImage sets the project image for an image name, with the source pathes and registry of the project
+func (project *Project) computeImage(imageName string, checkRegistry bool) error {
 	gitMain := project.GitMain
 
-	slog.Debug("Project source directories", "sourcePathes", project.SourcePathes)
+	slog.Debug("Project source directories", "image", imageName, "sourcePathes", project.SourcePathes)
+
+	if project.ImageTagStrategy == ImageTagContentHash {
+		return project.computeContentHashImage(imageName, checkRegistry)
+	}
+
+	dirtyFiles, err := project.getDirtySourceFiles()
+	if err != nil {
+		return err
+	}
+	if len(dirtyFiles) != 0 {
+		// The image in the registry does not contain the uncommitted changes
+		checkRegistry = false
+	}
 
 	head, err := gitMain.Repository.Head()
 	if err != nil {
 		return fmt.Errorf("unable to get HEAD of repository %s: %v", gitMain.Url, err)
 	}
-	gitMain.GetRoot()
 	hashes, err := FindCodeChange(gitMain.Repository, head.Hash(), project.SourcePathes)
 	if err != nil {
 		return fmt.Errorf("unable to find code change in repository %s: %v", gitMain.Url, err)
@@ -539,14 +932,6 @@ func (project *Project) GetImageName(suffix string, checkRegistry bool) error {
 		}
 		slog.Info("Project image with latest code changes", "hash", hashes[0], "version", rev.GetVersion())
 	}
-	imageName, err := project.GetName()
-	if err != nil {
-		return fmt.Errorf("unable to get project name: %v", err)
-	}
-	imageName = strings.ToLower(imageName)
-	if len(suffix) > 0 {
-		imageName = fmt.Sprintf("%s-%s", imageName, suffix)
-	}
 
 	image := Image{
 		Registry: project.ImageRegistry,
@@ -554,9 +939,10 @@ func (project *Project) GetImageName(suffix string, checkRegistry bool) error {
 	}
 	if checkRegistry {
 		inRegistryImage, err := project.findInRegistryImage(imageName, hashes)
-		// NOTE: it is difficult to filter the error here (network, image not exist, etc...)
-		// so in case of error we consider the image not in the registry
-		if inRegistryImage == nil || err != nil {
+		if err != nil {
+			return err
+		}
+		if inRegistryImage == nil {
 			image.InRegistry = false
 			slog.Debug("Image not found in registry", "image", image)
 		} else {
@@ -571,43 +957,250 @@ func (project *Project) GetImageName(suffix string, checkRegistry bool) error {
 		if err1 != nil {
 			return fmt.Errorf("unable to describe git repository: %v", err1)
 		}
+		// Dirty files outside of the source pathes are not in the image
+		rev.Dirty = len(dirtyFiles) != 0
 		image.Tag = rev.GetVersion()
-		if project.TemporaryRegistry != "" {
-			image.Registry = project.TemporaryRegistry
-		}
+		project.setImageToBuild(&image)
 	}
 
 	project.Image = image
 	return nil
 }
 
-// findInRegistryImage returns the first image which exist in the registry for a commit hash which is in hashes[]
-func (project *Project) findInRegistryImage(imageName string, hashes []plumbing.Hash) (*Image, error) {
+// setImageToBuild sets the untested tag and the temporary registry of an image which is not in the registry
+func (project *Project) setImageToBuild(image *Image) {
+	if project.Config.UntestedTags {
+		image.Tag = UntestedTag(image.Tag)
+		image.Untested = true
+	}
+	if project.TemporaryRegistry != "" {
+		image.Registry = project.TemporaryRegistry
+	}
+}
+
+// getDirtySourceFiles returns the tracked files of the worktree which are modified inside the source pathes,
+// the image must then be built with a dirty tag
+func (project *Project) getDirtySourceFiles() ([]string, error) {
+	dirtyFiles, err := project.GitMain.GetDirtySourceFiles(project.SourcePathes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get dirty source files: %v", err)
+	}
+	if len(dirtyFiles) != 0 {
+		slog.Info("Source files modified in the worktree, the image must be built", "files", dirtyFiles)
+	}
+	return dirtyFiles, nil
+}
+
+// GetContentHashTag returns the content-hash tag of the project image for HEAD,
+// with the dirty suffix if files are modified inside the source pathes
+func (project *Project) GetContentHashTag() (string, error) {
 	gitMain := project.GitMain
+	head, err := gitMain.Repository.Head()
+	if err != nil {
+		return "", fmt.Errorf("unable to get HEAD of repository %s: %v", gitMain.Url, err)
+	}
+	treeHash, err := GetSourceTreeHash(gitMain.Repository, head.Hash(), project.SourcePathes)
+	if err != nil {
+		return "", err
+	}
+	tag := ContentHashTagPrefix + treeHash[:contentHashTagLength]
+	dirtyFiles, err := project.getDirtySourceFiles()
+	if err != nil {
+		return "", err
+	}
+	if len(dirtyFiles) != 0 {
+		tag += "-dirty"
+	}
+	return tag, nil
+}
+
+// computeContentHashImage sets the project image for the content-hash tag strategy,
+// the tested image and then the untested one are probed in the registry
+func (project *Project) computeContentHashImage(imageName string, checkRegistry bool) error {
+	tag, err := project.GetContentHashTag()
+	if err != nil {
+		return err
+	}
+	slog.Info("Project image for source code content", "tag", tag)
 	image := Image{
 		Registry: project.ImageRegistry,
 		Name:     imageName,
+		Tag:      tag,
+	}
+	// A dirty image does not match its tag, it is always built
+	if checkRegistry && !strings.HasSuffix(tag, "-dirty") {
+		candidates := []Image{image}
+		if project.Config.UntestedTags {
+			candidates = append(candidates, Image{Registry: image.Registry, Name: imageName, Tag: UntestedTag(tag), Untested: true})
+		}
+		for _, candidate := range candidates {
+			found, err := project.probeImage(&candidate)
+			if err != nil {
+				err = project.handleRegistryError(err)
+				if err != nil {
+					return err
+				}
+				break
+			}
+			if found {
+				project.Image = candidate
+				return nil
+			}
+		}
+	}
+	project.setImageToBuild(&image)
+	project.Image = image
+	return nil
+}
+
+// GetImageBaseName returns the name of the project image, without registry and tag
+// a suffix can be added to image name
+func (project *Project) GetImageBaseName(suffix string) (string, error) {
+	imageName, err := project.GetName()
+	if err != nil {
+		return "", fmt.Errorf("unable to get project name: %v", err)
+	}
+	imageName = strings.ToLower(imageName)
+	if len(suffix) > 0 {
+		imageName = fmt.Sprintf("%s-%s", imageName, suffix)
 	}
+	return imageName, nil
+}
+
+// findInRegistryImage returns the first image which exist in the registry for a commit hash which is in hashes[]
+// if untested tags are enabled, an image with a tested tag is preferred over an image with an untested tag
+func (project *Project) findInRegistryImage(imageName string, hashes []plumbing.Hash) (*Image, error) {
+	gitMain := project.GitMain
+	var untestedImage *Image
 
 	for _, hash := range hashes {
 		rev, err := gitMain.GetRevision(hash)
 		if err != nil {
 			return nil, fmt.Errorf("unable to describe git repository for commit %v: %v", hash, err)
 		}
-		image.Tag = rev.GetVersion()
-		slog.Debug("Check image in registry", "image", image)
-		_, _, errRegistry := image.Desc()
-		if errRegistry != nil {
-			image.InRegistry = false
-		} else {
-			image.InRegistry = true
-			slog.Debug("Found image in registry", "image", image)
-			break
+		image := Image{
+			Registry: project.ImageRegistry,
+			Name:     imageName,
+			Tag:      rev.GetVersion(),
+		}
+		found, err := project.probeImage(&image)
+		if err != nil {
+			return nil, project.handleRegistryError(err)
+		}
+		if found {
+			return &image, nil
+		}
+		if project.Config.UntestedTags && untestedImage == nil {
+			image.Tag = UntestedTag(image.Tag)
+			image.Untested = true
+			found, err = project.probeImage(&image)
+			if err != nil {
+				return nil, project.handleRegistryError(err)
+			}
+			if found {
+				untestedImage = &image
+			}
 		}
 	}
-	if image.InRegistry {
-		return &image, nil
-	} else {
-		return nil, nil
+	return untestedImage, nil
+}
+
+// probeImage checks if an image exists in the registry, with all the required platforms, and set its InRegistry field
+// it returns an error if the registry can not tell if the image exists
+func (project *Project) probeImage(image *Image) (bool, error) {
+	slog.Debug("Check image in registry", "image", image)
+	image.InRegistry = false
+	image.Digest = ""
+	image.MissingPlatforms = nil
+	ref, err := ParseReference(image.Url())
+	if err != nil {
+		return false, fmt.Errorf("parsing reference %q: %w", image.Url(), err)
 	}
+	image.Digest, err = GetImageDigest(ref)
+	if errors.Is(err, ErrImageNotFound) {
+		image.Digest = ""
+		return false, nil
+	} else if err != nil {
+		image.Digest = ""
+		return false, err
+	}
+	missing, err := MissingPlatforms(ref, project.Platforms)
+	if err != nil {
+		image.Digest = ""
+		return false, err
+	}
+	if len(missing) != 0 {
+		image.MissingPlatforms = missing
+		slog.Warn("Image found in registry with missing platforms", "image", image.Url(), "missing", missing)
+		project.IgnoredImages = append(project.IgnoredImages, *image)
+		image.Digest = ""
+		return false, nil
+	}
+	outdated, err := project.outdatedBaseImages(ref)
+	if err != nil {
+		image.Digest = ""
+		return false, err
+	}
+	if len(outdated) != 0 {
+		image.OutdatedBaseImages = outdated
+		slog.Warn("Image found in registry with outdated base images", "image", image.Url(), "outdated", outdated)
+		project.IgnoredImages = append(project.IgnoredImages, *image)
+		image.Digest = ""
+		return false, nil
+	}
+	image.InRegistry = true
+	slog.Debug("Found image in registry", "image", image)
+	return true, nil
+}
+
+// GetBaseImageDigests returns the digests of the checked base images of the project Dockerfiles, by image name
+func (project *Project) GetBaseImageDigests() map[string]string {
+	digests := map[string]string{}
+	for _, dep := range project.Dependencies {
+		if dep.BaseImage != nil && dep.ImageDigest != "" {
+			digests[dep.Image] = dep.ImageDigest
+		}
+	}
+	return digests
+}
+
+// outdatedBaseImages returns the base images whose digest has changed since an image has been built,
+// according to its BaseImagesLabel label, images without this label are never outdated
+func (project *Project) outdatedBaseImages(ref name.Reference) ([]string, error) {
+	outdated := []string{}
+	current := project.GetBaseImageDigests()
+	if len(current) == 0 {
+		return outdated, nil
+	}
+	imageLabels, err := GetImageLabels(ref)
+	if err != nil {
+		return nil, err
+	}
+	label, found := imageLabels[BaseImagesLabel]
+	if !found {
+		slog.Debug("No base images label, base images are not compared", "image", ref, "label", BaseImagesLabel)
+		return outdated, nil
+	}
+	recorded := ParseBaseImagesLabel(label)
+	for baseImage, digest := range current {
+		if recorded[baseImage] != digest {
+			outdated = append(outdated, baseImage)
+		}
+	}
+	sort.Strings(outdated)
+	return outdated, nil
+}
+
+// handleRegistryError applies the registry error policy of the project,
+// it returns nil if the image has to be rebuilt
+func (project *Project) handleRegistryError(err error) error {
+	switch project.OnRegistryError {
+	case RegistryErrorFail:
+		return fmt.Errorf("unable to check image existence, use --on-registry-error=%s to build the image anyway: %w", RegistryErrorRebuild, err)
+	case RegistryErrorRebuild:
+		slog.Debug("Registry error, image will be built", "error", err)
+	default:
+		slog.Warn("Registry error, image will be built", "error", err)
+	}
+	return nil
 }
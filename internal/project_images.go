@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GetImageName returns the name of an additional image, i.e. its name or the project name with its suffix
+func (c ImageConfig) GetImageName(p *Project) (string, error) {
+	if c.Suffix != "" {
+		return p.GetImageBaseName(c.Suffix)
+	}
+	return strings.ToLower(c.Name), nil
+}
+
+// projectImageEnVarPrefix is the prefix of the CIUXCONFIG variables of the additional images
+const projectImageEnVarPrefix = "CIUX_IMAGE_"
+
+// GetProjectImageEnVarPrefix returns the prefix of the CIUXCONFIG variables of an additional image,
+// e.g. CIUX_IMAGE_FINK_BROKER_NOSCIENCE for the fink-broker-noscience image
+func GetProjectImageEnVarPrefix(imageName string) string {
+	replacer := strings.NewReplacer("-", "_", ".", "_", "/", "_")
+	return projectImageEnVarPrefix + strings.ToUpper(replacer.Replace(imageName))
+}
+
+// isImageConfigVar returns true if a CIUXCONFIG variable is the url of an image:
+// dependency images (<NAME>_IMAGE), project images (CIUX_IMAGE_URL, CIUX_PROMOTED_IMAGE_URL, ...)
+// and additional images (CIUX_IMAGE_<NAME>_URL)
+func isImageConfigVar(name string) bool {
+	return strings.HasSuffix(name, "_IMAGE") || strings.HasSuffix(name, "_IMAGE_URL") ||
+		(strings.HasPrefix(name, projectImageEnVarPrefix) && strings.HasSuffix(name, "_URL"))
+}
+
+// GetImagesNames computes the additional images of the 'images' section of the .ciux file, in one pass,
+// each image has its own source pathes and registry, which default to the ones of the project
+func (p *Project) GetImagesNames(checkRegistry bool) error {
+	images := []Image{}
+	for _, c := range p.Config.Images {
+		imageName, err := c.GetImageName(p)
+		if err != nil {
+			return err
+		}
+		imageProject := *p
+		imageProject.IgnoredImages = nil
+		if len(c.SourcePathes) != 0 || (p.Config.AutoSourcePathes != "" && c.Dockerfile != "") {
+			imageProject.SourcePathes = c.SourcePathes
+			if p.Config.Dockerignore || p.Config.AutoSourcePathes != "" {
+				root, err := p.GitMain.GetRoot()
+				if err != nil {
+					return fmt.Errorf("unable to get root of project repository: %v", err)
+				}
+				dockerfile := c.Dockerfile
+				if dockerfile == "" {
+					dockerfile = p.Config.GetDockerfile()
+				}
+				imageProject.SourcePathes, err = p.Config.resolveSourcePathes(root, c.SourcePathes, dockerfile)
+				if err != nil {
+					return fmt.Errorf("unable to compute source pathes of image %s: %w", imageName, err)
+				}
+			}
+		}
+		if c.Registry != "" {
+			imageProject.ImageRegistry = c.Registry
+		}
+		err = imageProject.computeImage(imageName, checkRegistry)
+		if err != nil {
+			return fmt.Errorf("unable to compute image %s: %w", imageName, err)
+		}
+		images = append(images, imageProject.Image)
+		p.IgnoredImages = append(p.IgnoredImages, imageProject.IgnoredImages...)
+	}
+	p.Images = images
+	return nil
+}
+
+// getImageDockerfile returns the Dockerfile of an additional image, from the 'images' section of the .ciux file
+func (p *Project) getImageDockerfile(imageName string) string {
+	for _, c := range p.Config.Images {
+		if name, err := c.GetImageName(p); err == nil && name == imageName {
+			return c.Dockerfile
+		}
+	}
+	return ""
+}
+
+// getImagesConfigVars returns the variables of the CIUXCONFIG file for the additional images
+func (p *Project) getImagesConfigVars() []ConfigVar {
+	vars := []ConfigVar{}
+	for _, image := range p.Images {
+		prefix := GetProjectImageEnVarPrefix(image.Name)
+		vars = append(vars,
+			ConfigVar{Name: prefix + "_URL", Value: image.Url()},
+			ConfigVar{Name: prefix + "_BUILD", Value: fmt.Sprintf("%t", !image.InRegistry), Comment: fmt.Sprintf("True if %s_URL need to be built", prefix)},
+		)
+		if dockerfile := p.getImageDockerfile(image.Name); dockerfile != "" {
+			vars = append(vars, ConfigVar{Name: prefix + "_DOCKERFILE", Value: dockerfile})
+		}
+	}
+	return vars
+}
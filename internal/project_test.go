@@ -3,13 +3,21 @@ package internal
 import (
 	"bufio"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/k8s-school/ciux/log"
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
@@ -454,3 +462,367 @@ func TestProjectGetName(t *testing.T) {
 		})
 	}
 }
+
+func TestGetImageNameRegistryError(t *testing.T) {
+	require := require.New(t)
+	t.Setenv("CIUXCONFIG", "")
+
+	localGit, _, _, err := setupTestProject("ciux-registryerror-test-")
+	require.NoError(err)
+	root, err := localGit.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	project, _, err := NewCoreProject(root, "")
+	require.NoError(err)
+	project.ImageRegistry = strings.TrimPrefix(unreachable.URL, "http://")
+
+	for _, policy := range []RegistryErrorPolicy{RegistryErrorRebuild, RegistryErrorWarn} {
+		project.OnRegistryError = policy
+		err = project.GetImageName("", true)
+		require.NoError(err)
+		require.False(project.Image.InRegistry)
+	}
+
+	project.OnRegistryError = RegistryErrorFail
+	err = project.GetImageName("", true)
+	require.ErrorIs(err, ErrRegistryUnreachable)
+
+	// Image not found is not a registry error
+	project.ImageRegistry = startTestRegistry(t)
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.False(project.Image.InRegistry)
+
+	_, err = ParseRegistryErrorPolicy("ignore")
+	require.Error(err)
+	policy, err := ParseRegistryErrorPolicy("")
+	require.NoError(err)
+	require.Equal(DefaultRegistryErrorPolicy, policy)
+}
+
+func TestGetImageNameDigest(t *testing.T) {
+	require := require.New(t)
+	t.Setenv("CIUXCONFIG", "")
+
+	localGit, _, _, err := setupTestProject("ciux-imagedigest-test-")
+	require.NoError(err)
+	root, err := localGit.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	project, _, err := NewCoreProject(root, "")
+	require.NoError(err)
+	project.ImageRegistry = startTestRegistry(t)
+
+	err = project.GetImageName("", false)
+	require.NoError(err)
+	require.Empty(project.Image.Digest)
+	require.Empty(project.Image.PinnedUrl())
+
+	img := pushRandomImage(t, project.Image.Url())
+	digest, err := img.Digest()
+	require.NoError(err)
+
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.True(project.Image.InRegistry)
+	require.Equal(digest.String(), project.Image.Digest)
+	require.Equal(project.ImageRegistry+"/"+project.Image.Name+"@"+digest.String(), project.Image.PinnedUrl())
+}
+
+func TestGetImageNamePlatforms(t *testing.T) {
+	require := require.New(t)
+	t.Setenv("CIUXCONFIG", "")
+
+	localGit, _, _, err := setupTestProject("ciux-platforms-test-")
+	require.NoError(err)
+	root, err := localGit.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	project, _, err := NewCoreProject(root, "")
+	require.NoError(err)
+	project.ImageRegistry = startTestRegistry(t)
+	project.Platforms = []string{"linux/amd64", "linux/arm64"}
+
+	err = project.GetImageName("", false)
+	require.NoError(err)
+	imageUrl := project.Image.Url()
+
+	// Only amd64 has been pushed
+	pushMultiPlatformImage(t, imageUrl, "linux/amd64")
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.False(project.Image.InRegistry)
+	require.Len(project.IgnoredImages, 1)
+	require.Equal(imageUrl, project.IgnoredImages[0].Url())
+	require.Equal([]string{"linux/arm64"}, project.IgnoredImages[0].MissingPlatforms)
+
+	pushMultiPlatformImage(t, imageUrl, "linux/amd64", "linux/arm64")
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.True(project.Image.InRegistry)
+	require.Empty(project.IgnoredImages)
+}
+
+func TestGetImageNameBaseImageDrift(t *testing.T) {
+	require := require.New(t)
+	t.Setenv("CIUXCONFIG", "")
+
+	localGit, _, _, err := setupTestProject("ciux-baseimagedrift-test-")
+	require.NoError(err)
+	root, err := localGit.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	project, _, err := NewCoreProject(root, "")
+	require.NoError(err)
+	registryUrl := startTestRegistry(t)
+	project.ImageRegistry = registryUrl
+	baseImage := registryUrl + "/base:v1"
+	project.Dependencies = []*Dependency{{Image: baseImage, BaseImage: &BaseImage{Name: baseImage, Dockerfile: "Dockerfile", Line: 1}}}
+
+	pushRandomImage(t, baseImage)
+	_, err = project.CheckBaseImages()
+	require.NoError(err)
+	label := FormatBaseImagesLabel(project.GetBaseImageDigests())
+	require.Equal(baseImage+"@"+project.Dependencies[0].ImageDigest, label)
+	require.Equal(project.GetBaseImageDigests(), ParseBaseImagesLabel(label))
+
+	err = project.GetImageName("", false)
+	require.NoError(err)
+	imageUrl := project.Image.Url()
+	img, err := random.Image(1024, 1)
+	require.NoError(err)
+	img, err = mutate.Config(img, v1.Config{Labels: map[string]string{BaseImagesLabel: label}})
+	require.NoError(err)
+	ref, err := name.ParseReference(imageUrl)
+	require.NoError(err)
+	require.NoError(remote.Write(ref, img))
+
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.True(project.Image.InRegistry)
+
+	// Base image is republished
+	pushRandomImage(t, baseImage)
+	_, err = project.CheckBaseImages()
+	require.NoError(err)
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.False(project.Image.InRegistry)
+	require.Len(project.IgnoredImages, 1)
+	require.Equal([]string{baseImage}, project.IgnoredImages[0].OutdatedBaseImages)
+
+	// Images without label are not compared
+	pushRandomImage(t, imageUrl)
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.True(project.Image.InRegistry)
+}
+
+func TestGetImageNameContentHash(t *testing.T) {
+	require := require.New(t)
+	t.Setenv("CIUXCONFIG", "")
+
+	localGit, _, _, err := setupTestProject("ciux-contenthash-test-")
+	require.NoError(err)
+	root, err := localGit.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	project, _, err := NewCoreProject(root, "")
+	require.NoError(err)
+	require.Equal(ImageTagGitDescribe, project.ImageTagStrategy)
+	project.ImageTagStrategy = ImageTagContentHash
+	project.ImageRegistry = startTestRegistry(t)
+	project.SourcePathes = []string{"src"}
+
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.False(project.Image.InRegistry)
+	require.True(strings.HasPrefix(project.Image.Tag, ContentHashTagPrefix))
+	imageUrl := project.Image.Url()
+	pushRandomImage(t, imageUrl)
+
+	// A commit outside of the source pathes keeps the same tag
+	_, _, err = localGit.TaggedCommit("other.txt", "other", "v1.1.0", true, author)
+	require.NoError(err)
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.True(project.Image.InRegistry)
+	require.Equal(imageUrl, project.Image.Url())
+	promotedImage, err := project.GetPromotedImage()
+	require.NoError(err)
+	require.Equal(project.Image.Tag, promotedImage.Tag)
+
+	// A source code change gives a new tag
+	require.NoError(os.MkdirAll(filepath.Join(root, "src"), 0755))
+	_, _, err = localGit.TaggedCommit("src/main.go", "code", "v1.2.0", true, author)
+	require.NoError(err)
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.False(project.Image.InRegistry)
+	require.NotEqual(imageUrl, project.Image.Url())
+
+	_, err = ParseImageTagStrategy("commit")
+	require.Error(err)
+}
+
+func TestGetImageNameDirty(t *testing.T) {
+	require := require.New(t)
+	t.Setenv("CIUXCONFIG", "")
+
+	localGit, _, _, err := setupTestProject("ciux-dirty-test-")
+	require.NoError(err)
+	root, err := localGit.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	project, _, err := NewCoreProject(root, "")
+	require.NoError(err)
+	project.ImageRegistry = startTestRegistry(t)
+	project.SourcePathes = []string{"src"}
+
+	require.NoError(os.MkdirAll(filepath.Join(root, "src"), 0755))
+	_, _, err = localGit.TaggedCommit("src/main.go", "code", "v1.1.0", true, author)
+	require.NoError(err)
+	_, _, err = localGit.TaggedCommit("other.txt", "other", "v1.2.0", true, author)
+	require.NoError(err)
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.False(project.Image.InRegistry)
+	pushRandomImage(t, project.Image.Url())
+	imageUrl := project.Image.Url()
+
+	// Dirty and untracked files outside of the source pathes are ignored
+	require.NoError(os.WriteFile(filepath.Join(root, "other.txt"), []byte("modified"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(root, "src", "untracked.go"), []byte("untracked"), 0644))
+	dirtyFiles, err := localGit.GetDirtySourceFiles(project.SourcePathes)
+	require.NoError(err)
+	require.Empty(dirtyFiles)
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.True(project.Image.InRegistry)
+	require.Equal(imageUrl, project.Image.Url())
+
+	// Dirty source files force the build of a dirty image
+	require.NoError(os.WriteFile(filepath.Join(root, "src", "main.go"), []byte("modified"), 0644))
+	dirtyFiles, err = localGit.GetDirtySourceFiles(project.SourcePathes)
+	require.NoError(err)
+	require.Equal([]string{filepath.Join("src", "main.go")}, dirtyFiles)
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.False(project.Image.InRegistry)
+	require.True(strings.HasSuffix(project.Image.Tag, "-dirty"))
+
+	project.ImageTagStrategy = ImageTagContentHash
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.False(project.Image.InRegistry)
+	require.True(strings.HasPrefix(project.Image.Tag, ContentHashTagPrefix))
+	require.True(strings.HasSuffix(project.Image.Tag, "-dirty"))
+}
+
+func TestGetImagesNames(t *testing.T) {
+	require := require.New(t)
+	t.Setenv("CIUXCONFIG", "")
+
+	localGit, _, _, err := setupTestProject("ciux-images-test-")
+	require.NoError(err)
+	root, err := localGit.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	project, _, err := NewCoreProject(root, "")
+	require.NoError(err)
+	project.ImageRegistry = startTestRegistry(t)
+	toolsRegistry := startTestRegistry(t)
+	project.Config.Images = []ImageConfig{
+		{Suffix: "noscience"},
+		{Name: "Tools", SourcePathes: []string{"tools"}, Registry: toolsRegistry, Dockerfile: "tools/Dockerfile"},
+	}
+	projectName, err := project.GetImageBaseName("")
+	require.NoError(err)
+
+	require.NoError(os.MkdirAll(filepath.Join(root, "tools"), 0755))
+	_, _, err = localGit.TaggedCommit("tools/tool.sh", "tools", "v1.1.0", true, author)
+	require.NoError(err)
+
+	err = project.GetImagesNames(true)
+	require.NoError(err)
+	require.Len(project.Images, 2)
+	require.Equal(projectName+"-noscience", project.Images[0].Name)
+	require.Equal(project.ImageRegistry, project.Images[0].Registry)
+	require.Equal("tools", project.Images[1].Name)
+	require.Equal(toolsRegistry, project.Images[1].Registry)
+	require.Equal("v1.1.0", project.Images[1].Tag)
+	pushRandomImage(t, project.Images[1].Url())
+
+	// Only the images which track the changed file are rebuilt
+	_, _, err = localGit.TaggedCommit("main.go", "code", "v1.2.0", true, author)
+	require.NoError(err)
+	err = project.GetImagesNames(true)
+	require.NoError(err)
+	require.False(project.Images[0].InRegistry)
+	require.Equal("v1.2.0", project.Images[0].Tag)
+	require.True(project.Images[1].InRegistry)
+	require.Equal("v1.1.0", project.Images[1].Tag)
+
+	prefix := GetProjectImageEnVarPrefix(projectName + "-noscience")
+	vars := map[string]string{}
+	for _, v := range project.getImagesConfigVars() {
+		vars[v.Name] = v.Value
+	}
+	require.Equal(project.Images[0].Url(), vars[prefix+"_URL"])
+	require.Equal("true", vars[prefix+"_BUILD"])
+	require.Equal(toolsRegistry+"/tools:v1.1.0", vars["CIUX_IMAGE_TOOLS_URL"])
+	require.Equal("false", vars["CIUX_IMAGE_TOOLS_BUILD"])
+	require.Equal("tools/Dockerfile", vars["CIUX_IMAGE_TOOLS_DOCKERFILE"])
+}
+
+func TestNewProjectUnresolvedVersion(t *testing.T) {
+	require := require.New(t)
+
+	localGit, remoteGitDeps, _, err := setupTestProject("ciux-unresolvedversion-test-")
+	require.NoError(err)
+	root, err := localGit.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+	depRoot, err := remoteGitDeps[0].GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(depRoot)
+
+	// No tag of the dependency matches the version constraint
+	ciuxConfig := "registry: test-registry.io\ndependencies:\n  - url: file://" + depRoot + "\n    version: \">=9.0.0\"\n"
+	require.NoError(os.WriteFile(filepath.Join(root, ".ciux"), []byte(ciuxConfig), 0644))
+
+	_, err = NewProject(root, "", false, "")
+	require.Error(err)
+	require.Contains(err.Error(), "unable to resolve ref for dependency repository")
+}
+
+func TestNewProjectUnmatchedBranchStrategy(t *testing.T) {
+	require := require.New(t)
+
+	localGit, remoteGitDeps, _, err := setupTestProject("ciux-unmatchedbranch-test-")
+	require.NoError(err)
+	root, err := localGit.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+	depRoot, err := remoteGitDeps[0].GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(depRoot)
+
+	// No branch of the dependency matches its branch strategy
+	ciuxConfig := "registry: test-registry.io\ndependencies:\n  - url: file://" + depRoot + "\n    branchStrategy: [\"missing\", \"release/*\"]\n"
+	require.NoError(os.WriteFile(filepath.Join(root, ".ciux"), []byte(ciuxConfig), 0644))
+
+	_, err = NewProject(root, "", false, "")
+	require.Error(err)
+	require.Contains(err.Error(), "no branch matches branch strategy")
+}
@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// GetPromotedImage returns the image which will be pushed if the CI run successfully,
+// its tag is the version of the project main repository, or the content-hash tag for the content-hash tag strategy
+func (p *Project) GetPromotedImage() (Image, error) {
+	var tag string
+	if p.ImageTagStrategy == ImageTagContentHash {
+		contentTag, err := p.GetContentHashTag()
+		if err != nil {
+			return Image{}, err
+		}
+		tag = contentTag
+	} else {
+		rev, err := p.GitMain.GetHeadRevision()
+		if err != nil {
+			return Image{}, fmt.Errorf("unable to describe git repository: %v", err)
+		}
+		tag = rev.GetVersion()
+	}
+	promotedImage := Image{
+		Registry: p.ImageRegistry,
+		Name:     p.Image.Name,
+		Tag:      tag,
+	}
+	return promotedImage, nil
+}
+
+// GetPromotionUrls returns the url of the image tested by the CI and the url of the promoted image
+// they are read from the CIUXCONFIG file if it exists, or computed from the project state
+func (p *Project) GetPromotionUrls(suffix string) (string, string, error) {
+	ciuxConfigFilepath, err := p.GetCiuxConfigFilepath()
+	if err != nil {
+		return "", "", fmt.Errorf("unable to get ciux config file path: %v", err)
+	}
+	if FileExists(ciuxConfigFilepath) {
+		vars, err := ReadConfigFile(ciuxConfigFilepath)
+		if err != nil {
+			return "", "", err
+		}
+		src, dst := vars["CIUX_IMAGE_URL"], vars["CIUX_PROMOTED_IMAGE_URL"]
+		if src == "" || dst == "" {
+			return "", "", fmt.Errorf("CIUX_IMAGE_URL or CIUX_PROMOTED_IMAGE_URL not found in configuration file %s", ciuxConfigFilepath)
+		}
+		slog.Debug("Read promotion urls", "file", ciuxConfigFilepath, "src", src, "dst", dst)
+		return src, dst, nil
+	}
+
+	err = p.GetImageName(suffix, true)
+	if err != nil {
+		return "", "", err
+	}
+	promotedImage, err := p.GetPromotedImage()
+	if err != nil {
+		return "", "", err
+	}
+	return p.Image.Url(), promotedImage.Url(), nil
+}
+
+// PromoteImage copies the image tested by the CI to the promoted image url,
+// only for a clean worktree on main/master branch
+func (p *Project) PromoteImage(src string, dst string, dryRun bool) (string, error) {
+	rev, err := p.GitMain.GetHeadRevision()
+	if err != nil {
+		return "", fmt.Errorf("unable to describe git repository: %v", err)
+	}
+	if p.ForcedBranch != "" {
+		rev.Branch = p.ForcedBranch
+	}
+	if !rev.IsPromotable() {
+		msg := fmt.Sprintf("Image %s not promoted: branch %s is not main/master or worktree is dirty", src, rev.Branch)
+		return msg, nil
+	}
+	if src == dst {
+		msg := fmt.Sprintf("Image %s already promoted", src)
+		return msg, nil
+	}
+	if dryRun {
+		slog.Info("Dry run:", "promote", src, "to", dst)
+		msg := fmt.Sprintf("Dry run: promote image %s to %s", src, dst)
+		return msg, nil
+	}
+	err = CopyImage(src, dst)
+	if err != nil {
+		return "", err
+	}
+	msg := fmt.Sprintf("Image %s promoted to %s", src, dst)
+	return msg, nil
+}
+
+// CopyImage copies an image, or a multi-architecture image index,
+// from a registry to another one without using a container engine
+func CopyImage(src string, dst string) error {
+	srcRef, err := ParseReference(src)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %w", src, err)
+	}
+	dstRef, err := ParseReference(dst)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %w", dst, err)
+	}
+	desc, err := remote.Get(srcRef, remoteOptions(srcRef.Context().RegistryStr())...)
+	if err != nil {
+		return fmt.Errorf("reading image %q: %w", srcRef, err)
+	}
+	if desc.MediaType.IsIndex() {
+		idx, err := desc.ImageIndex()
+		if err != nil {
+			return fmt.Errorf("reading image index %q: %w", srcRef, err)
+		}
+		err = remote.WriteIndex(dstRef, idx, remoteOptions(dstRef.Context().RegistryStr())...)
+		if err != nil {
+			return fmt.Errorf("writing image index %q: %w", dstRef, err)
+		}
+	} else {
+		img, err := desc.Image()
+		if err != nil {
+			return fmt.Errorf("reading image %q: %w", srcRef, err)
+		}
+		err = remote.Write(dstRef, img, remoteOptions(dstRef.Context().RegistryStr())...)
+		if err != nil {
+			return fmt.Errorf("writing image %q: %w", dstRef, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyImage(t *testing.T) {
+	require := require.New(t)
+	host := startTestRegistry(t)
+
+	// Single image
+	src := fmt.Sprintf("%s/tmp/project:v1.0.0-1-g1234567", host)
+	dst := fmt.Sprintf("%s/project:v1.0.0-1-g1234567", host)
+	img := pushRandomImage(t, src)
+	err := CopyImage(src, dst)
+	require.NoError(err)
+	dstImg, _, err := DescImage(dst)
+	require.NoError(err)
+	expectedDigest, err := img.Digest()
+	require.NoError(err)
+	actualDigest, err := dstImg.Digest()
+	require.NoError(err)
+	require.Equal(expectedDigest, actualDigest)
+
+	// Multi-architecture image
+	idx, err := random.Index(1024, 1, 2)
+	require.NoError(err)
+	srcIdx := fmt.Sprintf("%s/tmp/project:multiarch", host)
+	dstIdx := fmt.Sprintf("%s/project:multiarch", host)
+	ref, err := name.ParseReference(srcIdx)
+	require.NoError(err)
+	err = remote.WriteIndex(ref, idx)
+	require.NoError(err)
+	err = CopyImage(srcIdx, dstIdx)
+	require.NoError(err)
+	ref, err = name.ParseReference(dstIdx)
+	require.NoError(err)
+	digest, err := GetImageDigest(ref)
+	require.NoError(err)
+	expectedDigest, err = idx.Digest()
+	require.NoError(err)
+	require.Equal(expectedDigest.String(), digest)
+
+	// Source does not exist
+	err = CopyImage(fmt.Sprintf("%s/tmp/project:notexist", host), dst)
+	require.Error(err)
+}
+
+func TestPromoteImage(t *testing.T) {
+	require := require.New(t)
+	host := startTestRegistry(t)
+	t.Setenv("CIUXCONFIG", "")
+
+	localGit, _, _, err := setupTestProject("ciux-promoteimage-test-")
+	require.NoError(err)
+	root, err := localGit.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	project, _, err := NewCoreProject(root, "")
+	require.NoError(err)
+	project.ImageRegistry = host
+	project.TemporaryRegistry = host + "/tmp"
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.False(project.Image.InRegistry)
+	pushRandomImage(t, project.Image.Url())
+
+	src, dst, err := project.GetPromotionUrls("")
+	require.NoError(err)
+	require.Equal(project.Image.Url(), src)
+	promotedImage, err := project.GetPromotedImage()
+	require.NoError(err)
+	require.Equal(promotedImage.Url(), dst)
+	require.Equal(host, promotedImage.Registry)
+
+	// Urls are read from CIUXCONFIG file if it exists
+	_, err = project.WriteOutConfig()
+	require.NoError(err)
+	configSrc, configDst, err := project.GetPromotionUrls("")
+	require.NoError(err)
+	require.Equal(src, configSrc)
+	require.Equal(dst, configDst)
+
+	// Dry run does not copy the image
+	_, err = project.PromoteImage(src, dst, true)
+	require.NoError(err)
+	_, _, err = DescImage(dst)
+	require.Error(err)
+
+	_, err = project.PromoteImage(src, dst, false)
+	require.NoError(err)
+	_, _, err = DescImage(dst)
+	require.NoError(err)
+
+	// No promotion outside of main branches
+	dstBranch := fmt.Sprintf("%s/project-branch:v1.0.0", host)
+	project.ForcedBranch = "feature"
+	msg, err := project.PromoteImage(src, dstBranch, false)
+	require.NoError(err)
+	require.Contains(msg, "not promoted")
+	_, _, err = DescImage(dstBranch)
+	require.Error(err)
+}
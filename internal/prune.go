@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// GetImageRepositories returns the repositories of the project image, in the registry and in the temporary registry
+func (p *Project) GetImageRepositories(suffix string) ([]string, error) {
+	imageName, err := p.GetImageBaseName(suffix)
+	if err != nil {
+		return nil, err
+	}
+	repositories := []string{}
+	for _, registry := range []string{p.ImageRegistry, p.TemporaryRegistry} {
+		if registry == "" {
+			continue
+		}
+		repository := fmt.Sprintf("%s/%s", registry, imageName)
+		if !slices.Contains(repositories, repository) {
+			repositories = append(repositories, repository)
+		}
+	}
+	if len(repositories) == 0 {
+		return nil, fmt.Errorf("no registry defined for project image %s", imageName)
+	}
+	return repositories, nil
+}
+
+// PruneUntestedTags deletes the untested tags of a repository in the registry
+// an untested tag is deleted if its tested counterpart exists,
+// or if it has been created more than maxAge ago, a zero maxAge disables this check
+// the tags which can not be deleted, see tagDeleter, are reported in the returned error
+// it returns the deleted image urls
+func PruneUntestedTags(repository string, maxAge time.Duration, dryRun bool) ([]string, error) {
+	repo, err := NewRepository(repository)
+	if err != nil {
+		return nil, fmt.Errorf("parsing repository %q: %w", repository, err)
+	}
+	tags, err := remote.List(repo, remoteOptions(repo.RegistryStr())...)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags for %q: %w", repository, err)
+	}
+	existingTags := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		existingTags[tag] = true
+	}
+
+	toDelete := []string{}
+	reasons := map[string]string{}
+	retained := []string{}
+	for _, tag := range tags {
+		if !IsUntestedTag(tag) {
+			retained = append(retained, tag)
+			continue
+		}
+		ref := repo.Tag(tag)
+		reason := ""
+		if existingTags[TestedTag(tag)] {
+			reason = fmt.Sprintf("tested tag %s exists", TestedTag(tag))
+		} else if maxAge > 0 {
+			created, err := getImageCreationTime(ref)
+			if err != nil {
+				return nil, err
+			}
+			if created.IsZero() {
+				slog.Debug("Unknown creation time, keep untested tag", "image", ref)
+			} else if time.Since(created) > maxAge {
+				reason = fmt.Sprintf("created on %s", created.Format(time.RFC3339))
+			}
+		}
+		if reason == "" {
+			slog.Debug("Keep untested tag", "image", ref)
+			retained = append(retained, tag)
+			continue
+		}
+		toDelete = append(toDelete, tag)
+		reasons[tag] = reason
+	}
+
+	deleter := newTagDeleter(repo, retained)
+	deleted := []string{}
+	errs := []error{}
+	for _, tag := range toDelete {
+		ref := repo.Tag(tag)
+		if dryRun {
+			slog.Info("Dry run:", "delete", ref.String(), "reason", reasons[tag])
+		} else {
+			slog.Info("Delete untested tag", "image", ref.String(), "reason", reasons[tag])
+			err = deleter.delete(tag)
+			if err != nil {
+				slog.Warn("Unable to delete untested tag", "image", ref.String(), "error", err)
+				errs = append(errs, err)
+				continue
+			}
+		}
+		deleted = append(deleted, ref.String())
+	}
+	if len(errs) > 0 {
+		return deleted, fmt.Errorf("unable to delete %d untested tag(s) of %q: %w", len(errs), repository, errors.Join(errs...))
+	}
+	return deleted, nil
+}
+
+// tagDeleter deletes tags of a repository while keeping its retained tags
+type tagDeleter struct {
+	repo     name.Repository
+	retained []string
+	// Digests of the retained tags, retrieved on first use
+	digests map[string]string
+}
+
+func newTagDeleter(repo name.Repository, retained []string) *tagDeleter {
+	return &tagDeleter{repo: repo, retained: retained}
+}
+
+// delete deletes the reference of a tag if the registry supports it,
+// registry:2 rejects tag deletes, the manifest is then deleted by digest,
+// which is refused if a retained tag references the same digest, as it would be deleted too
+func (d *tagDeleter) delete(tag string) error {
+	ref := d.repo.Tag(tag)
+	options := remoteOptions(d.repo.RegistryStr())
+	desc, err := remote.Head(ref, options...)
+	if err != nil {
+		return fmt.Errorf("reading digest of image %q: %w", ref, err)
+	}
+	digest := desc.Digest.String()
+	err = remote.Delete(ref, options...)
+	if err == nil {
+		return nil
+	}
+	if !isUnsupportedError(err) {
+		return fmt.Errorf("deleting image %q: %w", ref, err)
+	}
+	slog.Debug("Registry does not support tag deletion, delete manifest", "image", ref.String(), "digest", digest)
+	if d.digests == nil {
+		d.digests, err = getTagsDigests(d.repo, d.retained)
+		if err != nil {
+			return err
+		}
+	}
+	if sharedTag := findTagWithDigest(d.digests, digest, tag); sharedTag != "" {
+		return fmt.Errorf("unable to delete image %q: registry does not support tag deletion and its digest %s is referenced by retained tag %s", ref, digest, sharedTag)
+	}
+	err = remote.Delete(d.repo.Digest(digest), options...)
+	if err != nil {
+		return fmt.Errorf("deleting manifest %s of image %q: %w", digest, ref, err)
+	}
+	return nil
+}
+
+// isUnsupportedError tells if a registry rejected an operation as unsupported
+func isUnsupportedError(err error) bool {
+	var terr *transport.Error
+	if !errors.As(err, &terr) {
+		return false
+	}
+	if terr.StatusCode == http.StatusMethodNotAllowed {
+		return true
+	}
+	for _, e := range terr.Errors {
+		if e.Code == transport.UnsupportedErrorCode {
+			return true
+		}
+	}
+	return false
+}
+
+// getTagsDigests returns the digests of the tags of a repository
+func getTagsDigests(repo name.Repository, tags []string) (map[string]string, error) {
+	digests := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		desc, err := remote.Head(repo.Tag(tag), remoteOptions(repo.RegistryStr())...)
+		if err != nil {
+			return nil, fmt.Errorf("reading digest of image %q: %w", repo.Tag(tag), err)
+		}
+		digests[tag] = desc.Digest.String()
+	}
+	return digests, nil
+}
+
+// findTagWithDigest returns a tag, other than the given one, which references a digest, or an empty string
+func findTagWithDigest(digests map[string]string, digest string, tag string) string {
+	for t, d := range digests {
+		if t != tag && d == digest {
+			return t
+		}
+	}
+	return ""
+}
+
+// getImageCreationTime returns the creation time of an image, as stored in its configuration
+func getImageCreationTime(ref name.Reference) (time.Time, error) {
+	img, err := remote.Image(ref, remoteOptions(ref.Context().RegistryStr())...)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("reading image %q: %w", ref, err)
+	}
+	config, err := img.ConfigFile()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("reading configuration for image %q: %w", ref, err)
+	}
+	return config.Created.Time, nil
+}
@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+)
+
+// pushImageCreatedAt pushes a random image with a given creation time to the given url
+func pushImageCreatedAt(t *testing.T, url string, created time.Time) {
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	img, err = mutate.CreatedAt(img, v1.Time{Time: created})
+	require.NoError(t, err)
+	ref, err := name.ParseReference(url)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+}
+
+func TestPruneUntestedTags(t *testing.T) {
+	require := require.New(t)
+	host := startTestRegistry(t)
+	repository := fmt.Sprintf("%s/project", host)
+
+	// Promoted
+	pushRandomImage(t, repository+":v1.0.0-untested")
+	pushRandomImage(t, repository+":v1.0.0")
+	// Old
+	pushImageCreatedAt(t, repository+":v1.0.0-1-g1234567-untested", time.Now().Add(-72*time.Hour))
+	// Recent
+	pushImageCreatedAt(t, repository+":v1.0.0-2-g89abcde-untested", time.Now().Add(-1*time.Hour))
+
+	deleted, err := PruneUntestedTags(repository, 48*time.Hour, true)
+	require.NoError(err)
+	require.ElementsMatch([]string{repository + ":v1.0.0-untested", repository + ":v1.0.0-1-g1234567-untested"}, deleted)
+	repo, err := name.NewRepository(repository)
+	require.NoError(err)
+	tags, err := remote.List(repo)
+	require.NoError(err)
+	require.Len(tags, 4)
+
+	deleted, err = PruneUntestedTags(repository, 0, false)
+	require.NoError(err)
+	require.Equal([]string{repository + ":v1.0.0-untested"}, deleted)
+
+	deleted, err = PruneUntestedTags(repository, 48*time.Hour, false)
+	require.NoError(err)
+	require.Equal([]string{repository + ":v1.0.0-1-g1234567-untested"}, deleted)
+
+	tags, err = remote.List(repo)
+	require.NoError(err)
+	require.ElementsMatch([]string{"v1.0.0", "v1.0.0-2-g89abcde-untested"}, tags)
+}
+
+func TestPruneUntestedTagsPromoted(t *testing.T) {
+	require := require.New(t)
+	host := startTestRegistry(t)
+	repository := fmt.Sprintf("%s/project", host)
+
+	// Promotion copies the manifest, the untested and the tested tags share the digest
+	pushRandomImage(t, repository+":v1.0.0-untested")
+	require.NoError(CopyImage(repository+":v1.0.0-untested", repository+":v1.0.0"))
+	tested, err := name.ParseReference(repository + ":v1.0.0")
+	require.NoError(err)
+	desc, err := remote.Head(tested)
+	require.NoError(err)
+
+	deleted, err := PruneUntestedTags(repository, 0, false)
+	require.NoError(err)
+	require.Equal([]string{repository + ":v1.0.0-untested"}, deleted)
+	repo, err := name.NewRepository(repository)
+	require.NoError(err)
+	tags, err := remote.List(repo)
+	require.NoError(err)
+	require.Equal([]string{"v1.0.0"}, tags)
+
+	// The tested image survives
+	descAfter, err := remote.Head(tested)
+	require.NoError(err)
+	require.Equal(desc.Digest, descAfter.Digest)
+	_, err = remote.Image(tested)
+	require.NoError(err)
+}
+
+func TestPruneUntestedTagsUnsupportedTagDeletion(t *testing.T) {
+	require := require.New(t)
+	host := startRegistryRejectingDeletes(t, false)
+	repository := fmt.Sprintf("%s/project", host)
+
+	// Promoted, the manifest can not be deleted without the tested tag
+	pushRandomImage(t, repository+":v1.0.0-untested")
+	require.NoError(CopyImage(repository+":v1.0.0-untested", repository+":v1.0.0"))
+	// Old, the manifest is deleted by digest
+	pushImageCreatedAt(t, repository+":v1.0.0-1-g1234567-untested", time.Now().Add(-72*time.Hour))
+
+	deleted, err := PruneUntestedTags(repository, 48*time.Hour, false)
+	require.Error(err)
+	require.Contains(err.Error(), "v1.0.0-untested")
+	require.Contains(err.Error(), "referenced by retained tag v1.0.0")
+	require.Equal([]string{repository + ":v1.0.0-1-g1234567-untested"}, deleted)
+
+	tested, err := name.ParseReference(repository + ":v1.0.0")
+	require.NoError(err)
+	_, err = remote.Image(tested)
+	require.NoError(err)
+}
+
+func TestGetImageNameUntested(t *testing.T) {
+	require := require.New(t)
+	host := startTestRegistry(t)
+
+	localGit, _, _, err := setupTestProject("ciux-untested-test-")
+	require.NoError(err)
+	root, err := localGit.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+
+	project, _, err := NewCoreProject(root, "")
+	require.NoError(err)
+	project.ImageRegistry = host
+	project.Config.UntestedTags = true
+
+	// Image to build
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.False(project.Image.InRegistry)
+	require.True(project.Image.Untested)
+	require.Equal("v1.0.0-untested", project.Image.Tag)
+	promotedImage, err := project.GetPromotedImage()
+	require.NoError(err)
+	require.Equal("v1.0.0", promotedImage.Tag)
+
+	// Untested image has been built
+	pushRandomImage(t, project.Image.Url())
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.True(project.Image.InRegistry)
+	require.True(project.Image.Untested)
+
+	// Tested image is preferred
+	pushRandomImage(t, promotedImage.Url())
+	err = project.GetImageName("", true)
+	require.NoError(err)
+	require.True(project.Image.InRegistry)
+	require.False(project.Image.Untested)
+	require.Equal("v1.0.0", project.Image.Tag)
+
+	repositories, err := project.GetImageRepositories("")
+	require.NoError(err)
+	require.Len(repositories, 1)
+	deleted, err := PruneUntestedTags(repositories[0], 0, false)
+	require.NoError(err)
+	require.Len(deleted, 1)
+}
@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/authn/github"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// RegistryConfig describes the credentials used by ciux for a registry
+type RegistryConfig struct {
+	// Registry hostname, with optional port, e.g. gitlab-registry.in2p3.fr
+	Host string `mapstructure:"host" default:""`
+	// User name, ignored if UsernameEnv is set
+	Username string `mapstructure:"username" default:""`
+	// Name of the environment variable which contains the user name
+	UsernameEnv string `mapstructure:"usernameEnv" default:""`
+	// Name of the environment variable which contains the password or a token, e.g. CI_JOB_TOKEN
+	PasswordEnv string `mapstructure:"passwordEnv" default:""`
+	// If true, the registry is reached over plain HTTP or with unverified TLS certificates
+	Insecure bool `mapstructure:"insecure" default:"false"`
+}
+
+// Environment variables used to authenticate to registries
+const (
+	// Optional, restricts CIUX_REGISTRY_USER and CIUX_REGISTRY_PASSWORD to a registry
+	RegistryEnv         = "CIUX_REGISTRY"
+	RegistryUserEnv     = "CIUX_REGISTRY_USER"
+	RegistryPasswordEnv = "CIUX_REGISTRY_PASSWORD"
+)
+
+// registryConfigs holds the registry credentials configuration of the current project
+var registryConfigs []RegistryConfig
+
+// insecureRegistries holds the registries set as insecure on the command line
+var insecureRegistries []string
+
+// SetRegistryConfigs sets the registry credentials configuration used by all registry calls
+func SetRegistryConfigs(configs []RegistryConfig) {
+	registryConfigs = configs
+}
+
+// SetInsecureRegistries sets the registries which are reached over plain HTTP or with unverified TLS certificates,
+// in addition to the ones with 'insecure: true' in the .ciux file
+func SetInsecureRegistries(hosts []string) {
+	insecureRegistries = hosts
+}
+
+// sameRegistry returns true if two registry hosts are equal, e.g. docker.io and index.docker.io
+func sameRegistry(host1 string, host2 string) bool {
+	normalize := func(host string) string {
+		reg, err := name.NewRegistry(host)
+		if err != nil {
+			return host
+		}
+		return reg.RegistryStr()
+	}
+	return normalize(host1) == normalize(host2)
+}
+
+// IsInsecureRegistry returns true if a registry is reached over plain HTTP or with unverified TLS certificates
+func IsInsecureRegistry(host string) bool {
+	for _, insecureHost := range insecureRegistries {
+		if sameRegistry(insecureHost, host) {
+			return true
+		}
+	}
+	for _, c := range registryConfigs {
+		if c.Insecure && sameRegistry(c.Host, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseReference parses an image reference, plain HTTP is allowed for insecure registries
+func ParseReference(s string) (name.Reference, error) {
+	ref, err := name.ParseReference(s)
+	if err != nil || !IsInsecureRegistry(ref.Context().RegistryStr()) {
+		return ref, err
+	}
+	return name.ParseReference(s, name.Insecure)
+}
+
+// NewRepository parses an image repository, plain HTTP is allowed for insecure registries
+func NewRepository(s string) (name.Repository, error) {
+	repo, err := name.NewRepository(s)
+	if err != nil || !IsInsecureRegistry(repo.RegistryStr()) {
+		return repo, err
+	}
+	return name.NewRepository(s, name.Insecure)
+}
+
+// Keychain returns the keychain used to authenticate to registries, credentials are searched in:
+//  1. the 'registries' section of the .ciux file
+//  2. CIUX_REGISTRY_USER and CIUX_REGISTRY_PASSWORD environment variables
+//  3. docker configuration file (~/.docker/config.json) and its credential helpers
+//  4. CI job tokens for GitLab CI registry (CI_REGISTRY) and GitHub Container Registry (GITHUB_TOKEN)
+func Keychain() authn.Keychain {
+	return authn.NewMultiKeychain(
+		envKeychain{configs: registryConfigs},
+		authn.DefaultKeychain,
+		gitlabKeychain{},
+		github.Keychain,
+	)
+}
+
+// remoteOptions returns the options used for all calls to a registry
+func remoteOptions(host string) []remote.Option {
+	options := []remote.Option{remote.WithAuthFromKeychain(Keychain())}
+	if IsInsecureRegistry(host) {
+		transport := remote.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		options = append(options, remote.WithTransport(transport))
+	}
+	return options
+}
+
+// envKeychain reads credentials from environment variables
+type envKeychain struct {
+	configs []RegistryConfig
+}
+
+func (k envKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	host := target.RegistryStr()
+	for _, c := range k.configs {
+		if !sameRegistry(c.Host, host) {
+			continue
+		}
+		username := c.Username
+		if c.UsernameEnv != "" {
+			username = os.Getenv(c.UsernameEnv)
+		}
+		password := ""
+		if c.PasswordEnv != "" {
+			password = os.Getenv(c.PasswordEnv)
+		}
+		if username != "" && password != "" {
+			slog.Debug("Registry credentials from ciux configuration", "registry", host, "username", username)
+			return &authn.Basic{Username: username, Password: password}, nil
+		}
+		slog.Debug("Incomplete registry credentials in ciux configuration", "registry", host, "usernameEnv", c.UsernameEnv, "passwordEnv", c.PasswordEnv)
+	}
+
+	registry := os.Getenv(RegistryEnv)
+	username := os.Getenv(RegistryUserEnv)
+	password := os.Getenv(RegistryPasswordEnv)
+	if username != "" && password != "" && (registry == "" || sameRegistry(registry, host)) {
+		slog.Debug("Registry credentials from environment", "registry", host, "username", username)
+		return &authn.Basic{Username: username, Password: password}, nil
+	}
+	return authn.Anonymous, nil
+}
+
+// gitlabKeychain reads the credentials of the GitLab CI registry, provided to GitLab CI jobs
+type gitlabKeychain struct{}
+
+func (gitlabKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	if os.Getenv("CI_REGISTRY") == "" || !sameRegistry(os.Getenv("CI_REGISTRY"), target.RegistryStr()) {
+		return authn.Anonymous, nil
+	}
+	if username, password := os.Getenv("CI_REGISTRY_USER"), os.Getenv("CI_REGISTRY_PASSWORD"); username != "" && password != "" {
+		return &authn.Basic{Username: username, Password: password}, nil
+	}
+	if token := os.Getenv("CI_JOB_TOKEN"); token != "" {
+		return &authn.Basic{Username: "gitlab-ci-token", Password: token}, nil
+	}
+	return authn.Anonymous, nil
+}
@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+)
+
+// resolveCredentials returns the credentials found by the ciux keychain for a registry
+func resolveCredentials(t *testing.T, registry string) *authn.AuthConfig {
+	reg, err := name.NewRegistry(registry)
+	require.NoError(t, err)
+	auth, err := Keychain().Resolve(reg)
+	require.NoError(t, err)
+	authConfig, err := auth.Authorization()
+	require.NoError(t, err)
+	return authConfig
+}
+
+func TestKeychain(t *testing.T) {
+	require := require.New(t)
+	// Ignore the docker configuration of the test environment
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	for _, env := range []string{RegistryEnv, RegistryUserEnv, RegistryPasswordEnv, "CI_REGISTRY", "CI_REGISTRY_USER", "CI_REGISTRY_PASSWORD", "CI_JOB_TOKEN", "GITHUB_TOKEN", "GITHUB_ACTOR"} {
+		t.Setenv(env, "")
+	}
+	defer SetRegistryConfigs(nil)
+
+	// Anonymous
+	authConfig := resolveCredentials(t, "registry.io")
+	require.Equal(&authn.AuthConfig{}, authConfig)
+
+	// GitLab CI job token
+	t.Setenv("CI_REGISTRY", "gitlab-registry.in2p3.fr")
+	t.Setenv("CI_JOB_TOKEN", "job-token")
+	authConfig = resolveCredentials(t, "gitlab-registry.in2p3.fr")
+	require.Equal("gitlab-ci-token", authConfig.Username)
+	require.Equal("job-token", authConfig.Password)
+	t.Setenv("CI_REGISTRY", "docker.io")
+	authConfig = resolveCredentials(t, "index.docker.io")
+	require.Equal("gitlab-ci-token", authConfig.Username)
+	t.Setenv("CI_REGISTRY", "gitlab-registry.in2p3.fr")
+
+	// GitHub token
+	t.Setenv("GITHUB_TOKEN", "github-token")
+	authConfig = resolveCredentials(t, "ghcr.io")
+	require.Equal("github-token", authConfig.Password)
+
+	// Environment variables restricted to a registry
+	t.Setenv(RegistryEnv, "registry.io")
+	t.Setenv(RegistryUserEnv, "ciux")
+	t.Setenv(RegistryPasswordEnv, "secret")
+	authConfig = resolveCredentials(t, "registry.io")
+	require.Equal("ciux", authConfig.Username)
+	require.Equal("secret", authConfig.Password)
+	authConfig = resolveCredentials(t, "other-registry.io")
+	require.Equal(&authn.AuthConfig{}, authConfig)
+
+	// Registry names are normalized, docker.io is index.docker.io
+	t.Setenv(RegistryEnv, "docker.io")
+	authConfig = resolveCredentials(t, "index.docker.io")
+	require.Equal("ciux", authConfig.Username)
+	t.Setenv(RegistryEnv, "registry.io")
+
+	// Ciux configuration has precedence
+	t.Setenv("MY_REGISTRY_USER", "fink")
+	t.Setenv("MY_REGISTRY_PASSWORD", "fink-secret")
+	SetRegistryConfigs([]RegistryConfig{{Host: "registry.io", UsernameEnv: "MY_REGISTRY_USER", PasswordEnv: "MY_REGISTRY_PASSWORD"}})
+	authConfig = resolveCredentials(t, "registry.io")
+	require.Equal("fink", authConfig.Username)
+	require.Equal("fink-secret", authConfig.Password)
+
+	// Incomplete ciux configuration falls back to other credentials
+	SetRegistryConfigs([]RegistryConfig{{Host: "registry.io", Username: "fink", PasswordEnv: "UNDEFINED_PASSWORD"}})
+	authConfig = resolveCredentials(t, "registry.io")
+	require.Equal("ciux", authConfig.Username)
+}
+
+func TestInsecureRegistry(t *testing.T) {
+	require := require.New(t)
+	defer SetInsecureRegistries(nil)
+	defer SetRegistryConfigs(nil)
+
+	url := "kind-registry:5000/project:v1.0.0"
+	ref, err := ParseReference(url)
+	require.NoError(err)
+	require.Equal("https", ref.Context().Scheme())
+
+	SetInsecureRegistries([]string{"kind-registry:5000"})
+	ref, err = ParseReference(url)
+	require.NoError(err)
+	require.Equal("http", ref.Context().Scheme())
+	repo, err := NewRepository("kind-registry:5000/project")
+	require.NoError(err)
+	require.Equal("http", repo.Scheme())
+
+	SetInsecureRegistries(nil)
+	SetRegistryConfigs([]RegistryConfig{{Host: "kind-registry:5000", Insecure: true}})
+	require.True(IsInsecureRegistry("kind-registry:5000"))
+	require.False(IsInsecureRegistry("registry.io"))
+	SetRegistryConfigs([]RegistryConfig{{Host: "docker.io", Insecure: true}})
+	require.True(IsInsecureRegistry("index.docker.io"))
+
+	// Registry with a self-signed certificate
+	SetRegistryConfigs(nil)
+	server := httptest.NewTLSServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+	url = fmt.Sprintf("%s/project:v1.0.0", host)
+	img, err := random.Image(1024, 1)
+	require.NoError(err)
+
+	SetInsecureRegistries([]string{host})
+	ref, err = ParseReference(url)
+	require.NoError(err)
+	require.NoError(remote.Write(ref, img, remoteOptions(host)...))
+	_, _, err = DescImage(url)
+	require.NoError(err)
+	tags, err := ListTags(fmt.Sprintf("%s/project", host))
+	require.NoError(err)
+	require.Equal([]string{"v1.0.0"}, tags)
+
+	SetInsecureRegistries(nil)
+	_, _, err = DescImage(url)
+	require.ErrorIs(err, ErrRegistryUnreachable)
+}
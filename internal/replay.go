@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// NewReplayProject creates a project from a lock file written by 'ciux ignite'
+// Dependencies are not scanned on their remote repositories
+// but set to the exact state recorded in the lock file
+// If lockFilepath is empty, the lock file for labelSelector is used
+func NewReplayProject(repositoryPath string, lockFilepath string, labelSelector string) (Project, LockFile, error) {
+	p, _, err := NewCoreProject(repositoryPath, "")
+	if err != nil {
+		return Project{}, LockFile{}, err
+	}
+
+	if lockFilepath == "" {
+		selector, err := labels.Parse(labelSelector)
+		if err != nil {
+			return Project{}, LockFile{}, fmt.Errorf("unable to parse label selector: %v", err)
+		}
+		p.Selector = selector
+		lockFilepath, err = p.GetLockFilepath()
+		if err != nil {
+			return Project{}, LockFile{}, fmt.Errorf("unable to get lock file path: %v", err)
+		}
+	}
+	lock, err := ReadLockFile(lockFilepath)
+	if err != nil {
+		return Project{}, LockFile{}, err
+	}
+	slog.Debug("Replay lock file", "file", lockFilepath)
+
+	p.Selector, err = labels.Parse(lock.Selector)
+	if err != nil {
+		return Project{}, LockFile{}, fmt.Errorf("unable to parse label selector %q from lock file: %v", lock.Selector, err)
+	}
+
+	head, err := p.GitMain.Repository.Head()
+	if err != nil {
+		return Project{}, LockFile{}, fmt.Errorf("unable to get HEAD of project main repository: %v", err)
+	}
+	if head.Hash().String() != lock.Main.Hash {
+		return Project{}, LockFile{}, fmt.Errorf("project main repository HEAD is %s but lock file records %s, checkout the recorded commit first", head.Hash(), lock.Main.Hash)
+	}
+	p.ForcedBranch = lock.Main.WorkBranch
+	p.GitMain.WorkBranch = lock.Main.WorkBranch
+
+	for _, locked := range lock.Dependencies {
+		dep := &Dependency{
+			Clone:       locked.Clone,
+			Pull:        locked.Pull,
+			Package:     locked.Package,
+			ImageDigest: locked.ImageDigest,
+
+			PackageVersion: locked.PackageVersion,
+		}
+		if locked.Git != nil {
+			dep.Git = &Git{
+				Url:        locked.Git.Url,
+				WorkBranch: locked.Git.WorkBranch,
+				RemoteHash: locked.Git.Hash,
+
+				Ref:          locked.Git.Ref,
+				VersionRange: locked.Git.VersionRange,
+			}
+		} else if locked.Image != "" {
+			dep.Image = locked.Image
+		}
+		p.Dependencies = append(p.Dependencies, dep)
+	}
+
+	p.Image = lock.Image.toImage()
+	for _, locked := range lock.Images {
+		p.Images = append(p.Images, locked.toImage())
+	}
+	return p, lock, nil
+}
+
+// RetrieveLockedDepsSources clones the dependencies and checks out the commits recorded in the lock file
+// The recorded commits of remote-only dependencies must still exist on their remote repository
+func (p *Project) RetrieveLockedDepsSources(basePath string) error {
+	slog.Debug("Retrieve locked dependencies sources locally", "basePath", basePath)
+	for _, dep := range p.Dependencies {
+		if dep.Git == nil {
+			continue
+		}
+		if dep.Git.RemoteHash == "" {
+			return fmt.Errorf("no commit recorded for git repository %s", dep.Git.Url)
+		}
+		if dep.Clone {
+			singleBranch := false
+			err := dep.Git.CloneOrOpen(basePath, singleBranch)
+			if err != nil {
+				return fmt.Errorf("unable to set git repository %s: %v", dep.Git.Url, err)
+			}
+			err = dep.Git.CheckoutHash(dep.Git.RemoteHash)
+			if err != nil {
+				return fmt.Errorf("unable to checkout recorded commit for git repository %s: %v", dep.Git.Url, err)
+			}
+		} else {
+			// Remote-only dependencies are not checked out, the recorded commit must still exist on the remote,
+			// even if their work branch has moved, the images pulled for them are checked by CheckLockedImages
+			err := dep.Git.LsRemote()
+			if err != nil {
+				return fmt.Errorf("unable to ls-remote for git repository %s: %v", dep.Git.Url, err)
+			}
+			found, err := dep.Git.HasRemoteCommit(dep.Git.RemoteHash)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return fmt.Errorf("recorded commit %s no longer exists in git repository %s", dep.Git.RemoteHash, dep.Git.Url)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckLockedImages checks that the images recorded in the lock file
+// still exist and still have the recorded digest
+func CheckLockedImages(lock LockFile) ([]name.Reference, error) {
+	foundImages := []name.Reference{}
+	for _, locked := range lock.Dependencies {
+		if locked.Image == "" {
+			continue
+		}
+		imageUrl := locked.Image
+		if locked.ImageMirror != "" {
+			imageUrl = locked.ImageMirror
+		}
+		ref, err := ParseReference(imageUrl)
+		if err != nil {
+			return foundImages, fmt.Errorf("parsing reference %q: %w", imageUrl, err)
+		}
+		// The digest request also checks the image existence
+		digest, err := GetImageDigest(ref)
+		if err != nil {
+			return foundImages, fmt.Errorf("unable to check image existence: %w", err)
+		}
+		if locked.ImageDigest != "" && digest != locked.ImageDigest {
+			return foundImages, fmt.Errorf("digest for image %s is %s but lock file records %s", imageUrl, digest, locked.ImageDigest)
+		}
+		foundImages = append(foundImages, ref)
+	}
+	return foundImages, nil
+}
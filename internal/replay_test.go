@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	require := require.New(t)
+
+	localGit, remoteGitDeps, _, err := setupTestProject("ciux-replay-test-")
+	require.NoError(err)
+	root, err := localGit.GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(root)
+	depRoot, err := remoteGitDeps[0].GetRoot()
+	require.NoError(err)
+	defer os.RemoveAll(depRoot)
+
+	project, err := NewProject(root, "", false, "build=true")
+	require.NoError(err)
+	tmpDir, err := os.MkdirTemp("", "ciux-replay-test-projectdeps-")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+	err = project.RetrieveDepsSources(tmpDir)
+	require.NoError(err)
+	err = project.GetImageName("", false)
+	require.NoError(err)
+	_, err = project.WriteLockFile()
+	require.NoError(err)
+	lockFilepath, err := project.GetLockFilepath()
+	require.NoError(err)
+
+	recordedHead, err := remoteGitDeps[0].Repository.Head()
+	require.NoError(err)
+
+	// Move the dependency work branch forward
+	newHash, _, err := remoteGitDeps[0].TaggedCommit("second.txt", "second", "v2.0.0", true, author)
+	require.NoError(err)
+	require.NotEqual(recordedHead.Hash(), *newHash)
+
+	replayProject, lock, err := NewReplayProject(root, "", "build=true")
+	require.NoError(err)
+	replayLockFilepath, err := replayProject.GetLockFilepath()
+	require.NoError(err)
+	require.Equal(lockFilepath, replayLockFilepath)
+	require.Len(replayProject.Dependencies, len(lock.Dependencies))
+
+	// Keep only the cloned dependency, the other one is remote-only and not reachable in test environment
+	replayProject.Dependencies = replayProject.Dependencies[:1]
+	replayDir, err := os.MkdirTemp("", "ciux-replay-test-replaydeps-")
+	require.NoError(err)
+	defer os.RemoveAll(replayDir)
+	err = replayProject.RetrieveLockedDepsSources(replayDir)
+	require.NoError(err)
+
+	replayedHead, err := replayProject.Dependencies[0].Git.Repository.Head()
+	require.NoError(err)
+	require.Equal(recordedHead.Hash(), replayedHead.Hash())
+
+	// A recorded commit which is no longer reachable must fail
+	replayProject.Dependencies[0].Git.Repository = nil
+	replayProject.Dependencies[0].Git.RemoteHash = "0123456789012345678901234567890123456789"
+	unreachableDir, err := os.MkdirTemp("", "ciux-replay-test-unreachable-")
+	require.NoError(err)
+	defer os.RemoveAll(unreachableDir)
+	err = replayProject.RetrieveLockedDepsSources(unreachableDir)
+	require.Error(err)
+
+	// The recorded commit of a remote-only dependency must still exist, its work branch can have moved
+	remoteOnly := Project{Dependencies: []*Dependency{{Git: &Git{Url: "file://" + depRoot, WorkBranch: "master", RemoteHash: newHash.String()}}}}
+	require.NoError(remoteOnly.RetrieveLockedDepsSources(unreachableDir))
+	remoteOnly.Dependencies[0].Git.RemoteHash = recordedHead.Hash().String()
+	require.NoError(remoteOnly.RetrieveLockedDepsSources(unreachableDir))
+	remoteOnly.Dependencies[0].Git.RemoteHash = "0123456789012345678901234567890123456789"
+	err = remoteOnly.RetrieveLockedDepsSources(unreachableDir)
+	require.Error(err)
+	require.Contains(err.Error(), "no longer exists")
+}
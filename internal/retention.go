@@ -0,0 +1,261 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// RetentionPolicy describes which image tags are kept in a registry
+type RetentionPolicy struct {
+	// Keep the tags of released versions, i.e. tags which match a git semver tag
+	KeepReleases bool
+	// Number of commits with an image which are kept for each branch, 0 means no limit
+	KeepLast int
+	// Delete the tags whose commit is not reachable from any branch
+	DeleteUnreachable bool
+}
+
+// RetentionDecision is the decision taken by the retention policy for an image tag
+type RetentionDecision struct {
+	Tag    string
+	Keep   bool
+	Reason string
+}
+
+// RetentionReport lists the decisions taken by the retention policy for all the tags of a repository
+type RetentionReport struct {
+	Repository string
+	Decisions  []RetentionDecision
+}
+
+func (r RetentionReport) String() string {
+	msg := fmt.Sprintf("Repository %s", r.Repository)
+	for _, d := range r.Decisions {
+		action := "keep"
+		if !d.Keep {
+			action = "delete"
+		}
+		msg += fmt.Sprintf("\n  %-6s %s (%s)", action, d.Tag, d.Reason)
+	}
+	return msg
+}
+
+// ToDelete returns the tags which have to be deleted
+func (r RetentionReport) ToDelete() []string {
+	tags := []string{}
+	for _, d := range r.Decisions {
+		if !d.Keep {
+			tags = append(tags, d.Tag)
+		}
+	}
+	return tags
+}
+
+// PlanRegistryRetention lists the tags of a repository in the registry
+// and applies the retention policy to them, using the history of a git repository
+func PlanRegistryRetention(repository string, g *Git, policy RetentionPolicy) (RetentionReport, error) {
+	repo, err := NewRepository(repository)
+	if err != nil {
+		return RetentionReport{}, fmt.Errorf("parsing repository %q: %w", repository, err)
+	}
+	tags, err := remote.List(repo, remoteOptions(repo.RegistryStr())...)
+	if err != nil {
+		return RetentionReport{}, fmt.Errorf("listing tags for %q: %w", repository, err)
+	}
+	report, err := PlanRetention(g, tags, policy)
+	if err != nil {
+		return RetentionReport{}, err
+	}
+	report.Repository = repository
+	return report, nil
+}
+
+// ApplyRetention deletes the tags of the repository which are not kept by the retention policy,
+// a tag whose manifest is shared with a kept tag is only deleted if the registry supports tag deletion, see tagDeleter
+func (r RetentionReport) ApplyRetention() error {
+	repo, err := NewRepository(r.Repository)
+	if err != nil {
+		return fmt.Errorf("parsing repository %q: %w", r.Repository, err)
+	}
+	retained := []string{}
+	for _, d := range r.Decisions {
+		if d.Keep {
+			retained = append(retained, d.Tag)
+		}
+	}
+	deleter := newTagDeleter(repo, retained)
+	errs := []error{}
+	for _, tag := range r.ToDelete() {
+		ref := repo.Tag(tag)
+		slog.Info("Delete image tag", "image", ref.String())
+		err = deleter.delete(tag)
+		if err != nil {
+			slog.Warn("Unable to delete image tag", "image", ref.String(), "error", err)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to delete %d tag(s) of %q: %w", len(errs), r.Repository, errors.Join(errs...))
+	}
+	return nil
+}
+
+// PlanRetention applies the retention policy to a list of image tags,
+// tags are parsed back to git revisions and their commits are searched in the branches of the git repository
+func PlanRetention(g *Git, tags []string, policy RetentionPolicy) (RetentionReport, error) {
+	branchCommits, err := g.getBranchCommits()
+	if err != nil {
+		return RetentionReport{}, err
+	}
+	semverTags, err := GitSemverTagMap(*g.Repository)
+	if err != nil {
+		return RetentionReport{}, fmt.Errorf("unable to get semver tags: %v", err)
+	}
+	taggedCommits := map[string]plumbing.Hash{}
+	for hash, ref := range *semverTags {
+		taggedCommits[ref.Name().Short()] = hash
+	}
+	shortHashes := map[string]plumbing.Hash{}
+	for _, commits := range branchCommits {
+		for _, hash := range commits {
+			shortHashes[hash.String()[0:7]] = hash
+		}
+	}
+
+	decisions := map[string]*RetentionDecision{}
+	tagsByCommit := map[plumbing.Hash][]string{}
+	for _, tag := range tags {
+		decision := RetentionDecision{Tag: tag}
+		decisions[tag] = &decision
+
+		rev, err := ParseGitRevision(TestedTag(tag))
+		if err != nil {
+			decision.Keep = true
+			decision.Reason = "not a git version"
+			continue
+		}
+		if policy.KeepReleases && rev.Counter == 0 && rev.Tag != "" && !rev.Dirty {
+			decision.Keep = true
+			decision.Reason = "release"
+			continue
+		}
+		var hash plumbing.Hash
+		var found bool
+		if rev.Counter == 0 && rev.Tag != "" {
+			hash, found = taggedCommits[rev.Tag]
+		} else {
+			hash, found = shortHashes[rev.Hash]
+		}
+		if !found {
+			decision.Keep = !policy.DeleteUnreachable
+			decision.Reason = "commit not reachable from any branch"
+			continue
+		}
+		tagsByCommit[hash] = append(tagsByCommit[hash], tag)
+	}
+
+	// Keep the last commits with an image for each branch
+	branches := make([]string, 0, len(branchCommits))
+	for branch := range branchCommits {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+	for _, branch := range branches {
+		rank := 0
+		for _, hash := range branchCommits[branch] {
+			commitTags, ok := tagsByCommit[hash]
+			if !ok {
+				continue
+			}
+			rank++
+			for _, tag := range commitTags {
+				decision := decisions[tag]
+				if decision.Keep {
+					continue
+				}
+				if policy.KeepLast == 0 || rank <= policy.KeepLast {
+					decision.Keep = true
+					decision.Reason = fmt.Sprintf("on branch %s", branch)
+					if policy.KeepLast != 0 {
+						decision.Reason = fmt.Sprintf("last %d on branch %s", policy.KeepLast, branch)
+					}
+				} else {
+					decision.Reason = fmt.Sprintf("older than last %d on its branches", policy.KeepLast)
+				}
+			}
+		}
+	}
+	for _, commitTags := range tagsByCommit {
+		for _, tag := range commitTags {
+			if decisions[tag].Reason == "" {
+				decisions[tag].Keep = !policy.DeleteUnreachable
+				decisions[tag].Reason = "commit not reachable from any branch"
+			}
+		}
+	}
+
+	report := RetentionReport{}
+	for _, tag := range tags {
+		report.Decisions = append(report.Decisions, *decisions[tag])
+	}
+	return report, nil
+}
+
+// getBranchCommits returns the commits of each branch, from the most recent one
+// remote-tracking branches are used if they exist, local branches otherwise
+func (g *Git) getBranchCommits() (map[string][]plumbing.Hash, error) {
+	refs, err := g.Repository.References()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list references: %v", err)
+	}
+	remoteBranches := map[string]plumbing.Hash{}
+	localBranches := map[string]plumbing.Hash{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+		if ref.Name().IsRemote() && !strings.HasSuffix(ref.Name().String(), "/HEAD") {
+			remoteBranches[ref.Name().Short()] = ref.Hash()
+		} else if ref.Name().IsBranch() {
+			localBranches[ref.Name().Short()] = ref.Hash()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list references: %v", err)
+	}
+	branches := remoteBranches
+	if len(branches) == 0 {
+		branches = localBranches
+	}
+
+	branchCommits := map[string][]plumbing.Hash{}
+	for branch, head := range branches {
+		cIter, err := g.Repository.Log(&git.LogOptions{
+			From:  head,
+			Order: git.LogOrderCommitterTime,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to get log for branch %s: %v", branch, err)
+		}
+		commits := []plumbing.Hash{}
+		err = cIter.ForEach(func(c *object.Commit) error {
+			commits = append(commits, c.Hash)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to loop on commits for branch %s, full git history is required: %v", branch, err)
+		}
+		slog.Debug("Branch history", "branch", branch, "commits", len(commits))
+		branchCommits[branch] = commits
+	}
+	return branchCommits, nil
+}
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRetentionRepo creates a git repository with a release, a main branch and a feature branch
+// it returns the image tags for each commit
+func setupRetentionRepo(t *testing.T) (Git, map[string]string) {
+	require := require.New(t)
+	gitObj, err := initGitRepo("ciux-retention-test-")
+	require.NoError(err)
+	root, err := gitObj.GetRoot()
+	require.NoError(err)
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	_, _, err = gitObj.TaggedCommit("first.txt", "first", "v1.0.0", true, author)
+	require.NoError(err)
+	worktree, err := gitObj.Repository.Worktree()
+	require.NoError(err)
+	when := time.Now()
+	commit := func(message string) plumbing.Hash {
+		when = when.Add(time.Minute)
+		signature := object.Signature{Name: author.Name, Email: author.Email, When: when}
+		hash, err := worktree.Commit(message, &git.CommitOptions{Author: &signature, AllowEmptyCommits: true})
+		require.NoError(err)
+		return hash
+	}
+	version := func(counter int, hash plumbing.Hash) string {
+		rev := GitRevision{Tag: "v1.0.0", Counter: counter, Hash: hash.String()}
+		return rev.GetVersion()
+	}
+
+	c2 := commit("c2")
+	require.NoError(gitObj.CreateBranch("feature"))
+	f1 := commit("f1")
+	f2 := commit("f2")
+	err = worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("master")})
+	require.NoError(err)
+	c3 := commit("c3")
+	c4 := commit("c4")
+
+	tags := map[string]string{}
+	tags["c1"] = "v1.0.0"
+	tags["c2"] = version(1, c2)
+	tags["c3"] = version(2, c3)
+	tags["c4"] = version(3, c4)
+	tags["f1"] = version(2, f1)
+	tags["f2"] = version(3, f2)
+	return gitObj, tags
+}
+
+func TestPlanRetention(t *testing.T) {
+	require := require.New(t)
+	gitObj, tags := setupRetentionRepo(t)
+
+	imageTags := []string{
+		tags["c1"],
+		tags["c2"],
+		tags["c3"],
+		tags["c4"],
+		UntestedTag(tags["c4"]),
+		tags["f1"],
+		tags["f2"],
+		"latest",
+		"v1.0.0-9-gdeadbee",
+	}
+	policy := RetentionPolicy{KeepReleases: true, KeepLast: 2, DeleteUnreachable: true}
+	report, err := PlanRetention(&gitObj, imageTags, policy)
+	require.NoError(err)
+	require.Len(report.Decisions, len(imageTags))
+	t.Logf("Report:\n%s", report)
+	require.ElementsMatch([]string{tags["c2"], "v1.0.0-9-gdeadbee"}, report.ToDelete())
+
+	// No limit on the number of tags per branch
+	policy = RetentionPolicy{KeepReleases: true, KeepLast: 0, DeleteUnreachable: false}
+	report, err = PlanRetention(&gitObj, imageTags, policy)
+	require.NoError(err)
+	require.Empty(report.ToDelete())
+
+	// Releases are subject to the per-branch limit
+	policy = RetentionPolicy{KeepReleases: false, KeepLast: 1, DeleteUnreachable: true}
+	report, err = PlanRetention(&gitObj, imageTags, policy)
+	require.NoError(err)
+	require.ElementsMatch([]string{tags["c1"], tags["c2"], tags["c3"], tags["f1"], "v1.0.0-9-gdeadbee"}, report.ToDelete())
+}
+
+func TestApplyRetention(t *testing.T) {
+	require := require.New(t)
+	host := startTestRegistry(t)
+	gitObj, tags := setupRetentionRepo(t)
+	repository := fmt.Sprintf("%s/project", host)
+
+	for _, tag := range []string{tags["c1"], tags["c2"], tags["c3"], tags["c4"]} {
+		pushRandomImage(t, repository+":"+tag)
+	}
+	policy := RetentionPolicy{KeepReleases: true, KeepLast: 1, DeleteUnreachable: true}
+	report, err := PlanRegistryRetention(repository, &gitObj, policy)
+	require.NoError(err)
+	// c2 is the latest image on the feature branch
+	require.Equal([]string{tags["c3"]}, report.ToDelete())
+	err = report.ApplyRetention()
+	require.NoError(err)
+
+	report, err = PlanRegistryRetention(repository, &gitObj, policy)
+	require.NoError(err)
+	require.Len(report.Decisions, 3)
+	require.Empty(report.ToDelete())
+}
+
+func TestParseGitRevision(t *testing.T) {
+	require := require.New(t)
+
+	revisions := []GitRevision{
+		{Tag: "v1.0.0", Counter: 1, Hash: "1234567"},
+		{Tag: "v1.0.0-rc1", Counter: 12, Hash: "89abcde", Dirty: true},
+		{Tag: "v1.0.0", Counter: 0, Hash: ""},
+		{Tag: "v1.0.0-rc1", Counter: 0, Hash: "", Dirty: true},
+		{Tag: "", Counter: 0, Hash: "1234567"},
+	}
+	for _, rev := range revisions {
+		parsed, err := ParseGitRevision(rev.GetVersion())
+		require.NoError(err)
+		require.Equal(rev, *parsed)
+	}
+
+	_, err := ParseGitRevision("latest")
+	require.Error(err)
+}
+
+func TestApplyRetentionUnsupportedTagDeletion(t *testing.T) {
+	require := require.New(t)
+	gitObj, tags := setupRetentionRepo(t)
+	policy := RetentionPolicy{KeepReleases: true, KeepLast: 1, DeleteUnreachable: true}
+
+	// The manifest of the deleted tag is deleted by digest
+	host := startRegistryRejectingDeletes(t, false)
+	repository := fmt.Sprintf("%s/project", host)
+	for _, tag := range []string{tags["c1"], tags["c2"], tags["c4"]} {
+		pushRandomImage(t, repository+":"+tag)
+	}
+	img := pushRandomImage(t, repository+":"+tags["c3"])
+	digest, err := img.Digest()
+	require.NoError(err)
+	report, err := PlanRegistryRetention(repository, &gitObj, policy)
+	require.NoError(err)
+	require.Equal([]string{tags["c3"]}, report.ToDelete())
+	require.NoError(report.ApplyRetention())
+	ref, err := name.ParseReference(repository + "@" + digest.String())
+	require.NoError(err)
+	_, err = remote.Head(ref)
+	require.Error(err)
+
+	// The manifest of the deleted tag is shared with a retained tag
+	host = startRegistryRejectingDeletes(t, false)
+	repository = fmt.Sprintf("%s/project", host)
+	for _, tag := range []string{tags["c1"], tags["c2"], tags["c4"]} {
+		pushRandomImage(t, repository+":"+tag)
+	}
+	require.NoError(CopyImage(repository+":"+tags["c4"], repository+":"+tags["c3"]))
+	report, err = PlanRegistryRetention(repository, &gitObj, policy)
+	require.NoError(err)
+	err = report.ApplyRetention()
+	require.Error(err)
+	require.Contains(err.Error(), "referenced by retained tag "+tags["c4"])
+	ref, err = name.ParseReference(repository + ":" + tags["c4"])
+	require.NoError(err)
+	_, err = remote.Image(ref)
+	require.NoError(err)
+
+	// The registry does not support deletion
+	host = startRegistryRejectingDeletes(t, true)
+	repository = fmt.Sprintf("%s/project", host)
+	for _, tag := range []string{tags["c1"], tags["c2"], tags["c3"], tags["c4"]} {
+		pushRandomImage(t, repository+":"+tag)
+	}
+	report, err = PlanRegistryRetention(repository, &gitObj, policy)
+	require.NoError(err)
+	err = report.ApplyRetention()
+	require.Error(err)
+	require.Contains(err.Error(), "UNSUPPORTED")
+}
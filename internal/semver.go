@@ -104,3 +104,135 @@ func equalStringSlice(a, b []string) bool {
 	}
 	return true
 }
+
+// Compare returns -1, 0 or +1 if v is lower, equal or greater than v2, the prefix and the build metadata are ignored,
+// a pre-release version is lower than the release version
+func (v SemVer) Compare(v2 SemVer) int {
+	for _, c := range [][2]int{{v.Major, v2.Major}, {v.Minor, v2.Minor}, {v.Patch, v2.Patch}} {
+		if c[0] != c[1] {
+			if c[0] < c[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(v.Prerelease) == 0 && len(v2.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(v2.Prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(v2.Prerelease); i++ {
+		if c := comparePrerelease(v.Prerelease[i], v2.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(v.Prerelease) < len(v2.Prerelease):
+		return -1
+	case len(v.Prerelease) > len(v2.Prerelease):
+		return 1
+	}
+	return 0
+}
+
+// comparePrerelease compares pre-release identifiers, numeric identifiers are lower than alphanumeric ones
+func comparePrerelease(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		if na == nb {
+			return 0
+		} else if na < nb {
+			return -1
+		}
+		return 1
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// SemVerConstraint is a comparison with a version, e.g. ">=3.1.0"
+type SemVerConstraint struct {
+	Operator string
+	Version  SemVer
+}
+
+// SemVerRange is a list of constraints which must all be satisfied, e.g. ">=3.1.0 <4.0.0"
+type SemVerRange []SemVerConstraint
+
+var semVerConstraintRegexp = regexp.MustCompile(`^(>=|<=|>|<|=)?(.+)$`)
+
+// ParseSemVerRange parses a version range, i.e. constraints separated by spaces or commas,
+// with the operators >=, >, <=, < and =, which is the default
+func ParseSemVerRange(str string) (SemVerRange, error) {
+	r := SemVerRange{}
+	for _, field := range strings.FieldsFunc(str, func(c rune) bool { return c == ' ' || c == ',' }) {
+		match := semVerConstraintRegexp.FindStringSubmatch(field)
+		v := SemVerParse(match[2])
+		if v == nil {
+			return nil, fmt.Errorf("invalid version %q in version range %q", match[2], str)
+		}
+		operator := match[1]
+		if operator == "" {
+			operator = "="
+		}
+		r = append(r, SemVerConstraint{Operator: operator, Version: *v})
+	}
+	if len(r) == 0 {
+		return nil, fmt.Errorf("empty version range %q", str)
+	}
+	return r, nil
+}
+
+// Contains returns true if a version satisfies all the constraints of the range,
+// pre-release versions only satisfy the range if one of its constraints is a pre-release of the same version
+func (r SemVerRange) Contains(v SemVer) bool {
+	prereleaseAllowed := len(v.Prerelease) == 0
+	for _, c := range r {
+		cmp := v.Compare(c.Version)
+		var ok bool
+		switch c.Operator {
+		case ">=":
+			ok = cmp >= 0
+		case ">":
+			ok = cmp > 0
+		case "<=":
+			ok = cmp <= 0
+		case "<":
+			ok = cmp < 0
+		default:
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+		if len(c.Version.Prerelease) != 0 && c.Version.Major == v.Major && c.Version.Minor == v.Minor && c.Version.Patch == v.Patch {
+			prereleaseAllowed = true
+		}
+	}
+	return prereleaseAllowed
+}
+
+// MaxSatisfying returns the highest semver tag which satisfies the range, or an empty string if there is none
+func (r SemVerRange) MaxSatisfying(tags []string) string {
+	var maxTag string
+	var maxVersion *SemVer
+	for _, tag := range tags {
+		v := SemVerParse(tag)
+		if v == nil || !r.Contains(*v) {
+			continue
+		}
+		if maxVersion == nil || v.Compare(*maxVersion) > 0 {
+			maxTag = tag
+			maxVersion = v
+		}
+	}
+	return maxTag
+}
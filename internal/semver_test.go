@@ -81,3 +81,52 @@ func TestSemVerGetReleaseCandidateNum(t *testing.T) {
 	// Test case for empty prerelease slice
 	test(SemVer{}, -1, nil)
 }
+
+func TestSemVerCompare(t *testing.T) {
+	assert := assert.New(t)
+	test := func(a string, b string, expected int) {
+		actual := SemVerParse(a).Compare(*SemVerParse(b))
+		assert.Equal(expected, actual, "%s %s", a, b)
+	}
+
+	test("v1.2.3", "1.2.3", 0)
+	test("v1.2.3", "v1.2.4", -1)
+	test("v1.10.0", "v1.9.0", 1)
+	test("v2.0.0", "v1.9.9", 1)
+	test("v1.0.0-rc1", "v1.0.0", -1)
+	test("v1.0.0-rc.2", "v1.0.0-rc.10", -1)
+	test("v1.0.0-rc.1", "v1.0.0-rc.1.1", -1)
+	test("v1.0.0-1", "v1.0.0-alpha", -1)
+	test("v1.0.0+build", "v1.0.0", 0)
+}
+
+func TestSemVerRange(t *testing.T) {
+	assert := assert.New(t)
+	test := func(versionRange string, version string, expected bool) {
+		r, err := ParseSemVerRange(versionRange)
+		assert.NoError(err)
+		actual := r.Contains(*SemVerParse(version))
+		assert.Equal(expected, actual, "%s %s", versionRange, version)
+	}
+
+	test(">=3.1.0 <4.0.0", "v3.1.0", true)
+	test(">=3.1.0 <4.0.0", "v3.9.2", true)
+	test(">=3.1.0 <4.0.0", "v4.0.0", false)
+	test(">=3.1.0, <4.0.0", "v3.0.9", false)
+	test(">3.1.0", "v3.1.0", false)
+	test("<=3.1.0", "v3.1.0", true)
+	test("v3.1.0", "3.1.0", true)
+	test("=3.1.0", "v3.1.1", false)
+	test(">=3.1.0 <4.0.0", "v3.2.0-rc1", false)
+	test(">=3.2.0-rc1 <4.0.0", "v3.2.0-rc2", true)
+
+	for _, invalid := range []string{"", ">=", ">=3.1", "~3.1.0"} {
+		_, err := ParseSemVerRange(invalid)
+		assert.Error(err, invalid)
+	}
+
+	r, err := ParseSemVerRange(">=3.1.0 <4.0.0")
+	assert.NoError(err)
+	assert.Equal("v3.10.0", r.MaxSatisfying([]string{"v3.0.0", "v3.2.0", "v3.10.0", "v3.9.0", "v4.0.0", "v3.11.0-rc1", "latest"}))
+	assert.Equal("", r.MaxSatisfying([]string{"v2.0.0", "latest"}))
+}
@@ -3,6 +3,7 @@ package internal
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"log/slog"
 	"os/exec"
 )
@@ -10,23 +11,39 @@ import (
 const shell = "bash"
 
 func ExecCmd(command string, dryRun bool) (string, string, error) {
+	stdout, stderr, err := execCmd(exec.Command(shell, "-c", command), dryRun)
+	if err != nil {
+		err = fmt.Errorf("failed to run command %s in shell: %s", command, err)
+	}
+	return stdout, stderr, err
+}
+
+// ExecCmdArgs runs a command without shell, argv is the command followed by its arguments and stdin is optional
+func ExecCmdArgs(stdin io.Reader, dryRun bool, argv ...string) (string, string, error) {
+	if len(argv) == 0 {
+		return "", "", fmt.Errorf("no command to run")
+	}
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = stdin
+	stdout, stderr, err := execCmd(cmd, dryRun)
+	if err != nil {
+		err = fmt.Errorf("failed to run command %s: %v, %s", cmd.String(), err, stderr)
+	}
+	return stdout, stderr, err
+}
+
+func execCmd(cmd *exec.Cmd, dryRun bool) (string, string, error) {
 
 	var outErr error
 	stderrBuf := new(bytes.Buffer)
 	stdoutBuf := new(bytes.Buffer)
 	if !dryRun {
-		cmd := exec.Command(shell, "-c", command)
 		cmd.Stdout = stdoutBuf
 		cmd.Stderr = stderrBuf
-		err := cmd.Run()
-		if err != nil {
-			outErr = fmt.Errorf("failed to run command %s in shell: %s", command, err)
-		}
-		// logger.Infof("stdout %v", stdoutBuf)
-		// logger.Infof("stderr %v", stderrBuf)
-
+		slog.Debug("Run command", "command", cmd.String())
+		outErr = cmd.Run()
 	} else {
-		slog.Info("Dry run:", "command", command)
+		slog.Info("Dry run:", "command", cmd.String())
 	}
 	return stdoutBuf.String(), stderrBuf.String(), outErr
 }
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -30,3 +31,28 @@ func TestExecCmd(t *testing.T) {
 	require.Equal("", stdout)
 	require.Equal("", stderr)
 }
+
+func TestExecCmdArgs(t *testing.T) {
+	require := require.New(t)
+
+	// Arguments are not interpreted by a shell
+	stdout, _, err := ExecCmdArgs(nil, false, "echo", "$HOME", "a b")
+	require.NoError(err)
+	require.Equal("$HOME a b\n", stdout)
+
+	stdout, _, err = ExecCmdArgs(strings.NewReader("from stdin"), false, "cat")
+	require.NoError(err)
+	require.Equal("from stdin", stdout)
+
+	_, stderr, err := ExecCmdArgs(nil, false, "ls", "/nonexistent")
+	require.Error(err)
+	require.NotEmpty(stderr)
+	require.Contains(err.Error(), stderr)
+
+	stdout, _, err = ExecCmdArgs(nil, true, "nonexistent-command")
+	require.NoError(err)
+	require.Equal("", stdout)
+
+	_, _, err = ExecCmdArgs(nil, false)
+	require.Error(err)
+}
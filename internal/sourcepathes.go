@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sourceRule is an entry of sourcePathes
+// plain entries are files or directories relative to the repository root, like "Dockerfile" or "fink_broker",
+// other entries are gitignore-style patterns:
+//   - a leading '!' excludes the matching files, e.g. "!**/*.md"
+//   - a trailing '/' only matches directories, e.g. "!tests/"
+//   - a pattern without '/', except a trailing one, matches at any depth, e.g. "*.py"
+//   - '*' and '?' do not match '/', '**' matches any number of directories
+type sourceRule struct {
+	exclude bool
+	// plain is the path of a plain entry, "." for the whole repository
+	plain string
+	re    *regexp.Regexp
+	// dirOnly patterns only match the directories of a file path
+	dirOnly bool
+}
+
+// SourceMatcher tells if a file belongs to the sourcePathes of a project,
+// the last matching entry wins, and if there is no include entry all the files are included by default.
+// The .dockerignore entries, see DockerignorePatterns, can only exclude files from the sourcePathes.
+type SourceMatcher struct {
+	rules      []sourceRule
+	hasInclude bool
+	// Rules of the .dockerignore entries, in .dockerignore polarity: an exclude rule is a re-include ('!') line
+	ignoreRules []sourceRule
+}
+
+// dockerignorePrefix is the prefix of the sourcePathes entries which come from a .dockerignore file
+const dockerignorePrefix = "dockerignore:"
+
+// isSourcePattern returns true if a sourcePathes entry is a pattern, and not a plain file or directory
+func isSourcePattern(sourcePath string) bool {
+	return strings.HasPrefix(sourcePath, "!") || strings.HasPrefix(sourcePath, "/") ||
+		strings.HasSuffix(sourcePath, "/") || strings.ContainsAny(sourcePath, "*?[") ||
+		strings.HasPrefix(sourcePath, dockerignorePrefix)
+}
+
+// NewSourceMatcher returns the matcher for sourcePathes entries
+func NewSourceMatcher(sourcePathes []string) (*SourceMatcher, error) {
+	m := SourceMatcher{}
+	for _, sourcePath := range sourcePathes {
+		if pattern, found := strings.CutPrefix(sourcePath, dockerignorePrefix); found {
+			rule, err := newSourceRule(pattern)
+			if err != nil {
+				return nil, err
+			}
+			m.ignoreRules = append(m.ignoreRules, rule)
+			continue
+		}
+		rule, err := newSourceRule(sourcePath)
+		if err != nil {
+			return nil, err
+		}
+		if !rule.exclude {
+			m.hasInclude = true
+		}
+		m.rules = append(m.rules, rule)
+	}
+	return &m, nil
+}
+
+func newSourceRule(sourcePath string) (sourceRule, error) {
+	if !isSourcePattern(sourcePath) {
+		return sourceRule{plain: path.Clean(sourcePath)}, nil
+	}
+	rule := sourceRule{}
+	pattern := sourcePath
+	if strings.HasPrefix(pattern, "!") {
+		rule.exclude = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		return rule, fmt.Errorf("invalid source path pattern %q", sourcePath)
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		if filepath.IsAbs(pattern) || pattern != path.Clean(pattern) || strings.HasPrefix(pattern, "..") {
+			return rule, fmt.Errorf("source path %s must be relative and clean", sourcePath)
+		}
+	}
+
+	expr := sourceGlobToRegexp(pattern)
+	if anchored {
+		expr = "^" + expr + "$"
+	} else {
+		expr = "^(.*/)?" + expr + "$"
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return rule, fmt.Errorf("invalid source path pattern %q: %v", sourcePath, err)
+	}
+	rule.re = re
+	return rule, nil
+}
+
+// sourceGlobToRegexp converts a gitignore-style glob to a regular expression,
+// where '**/' matches zero or more directories
+func sourceGlobToRegexp(glob string) string {
+	segments := strings.Split(glob, "/")
+	var expr strings.Builder
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		if segment == "**" {
+			if last {
+				expr.WriteString(".*")
+			} else {
+				expr.WriteString("(.*/)?")
+			}
+			continue
+		}
+		expr.WriteString(globToRegexp(segment))
+		if !last {
+			expr.WriteString("/")
+		}
+	}
+	return expr.String()
+}
+
+// match returns true if a rule matches a file path, or one of its parent directories
+func (r sourceRule) match(filePath string) bool {
+	if r.re == nil {
+		return r.plain == "." || filePath == r.plain || strings.HasPrefix(filePath, r.plain+"/")
+	}
+	if !r.dirOnly && r.re.MatchString(filePath) {
+		return true
+	}
+	for dir := path.Dir(filePath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if r.re.MatchString(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns true if a file path, relative to the repository root and with '/' separators, belongs to the source pathes
+func (m *SourceMatcher) Match(filePath string) bool {
+	if filePath == "" {
+		return false
+	}
+	included := !m.hasInclude
+	for _, rule := range m.rules {
+		if rule.match(filePath) {
+			included = !rule.exclude
+		}
+	}
+	if !included {
+		return false
+	}
+	// .dockerignore lines, the last matching one wins
+	ignored := false
+	for _, rule := range m.ignoreRules {
+		if rule.match(filePath) {
+			ignored = !rule.exclude
+		}
+	}
+	return !ignored
+}
+
+// IsPlain returns true if all the entries are plain files or directories
+func (m *SourceMatcher) IsPlain() bool {
+	if len(m.ignoreRules) != 0 {
+		return false
+	}
+	for _, rule := range m.rules {
+		if rule.re != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// DockerignorePatterns returns the .dockerignore patterns of the build context of a Dockerfile as sourcePathes entries,
+// the build context is the directory of the Dockerfile, and <Dockerfile>.dockerignore is preferred over .dockerignore.
+// The entries are the .dockerignore lines, anchored to the repository root and prefixed with "dockerignore:":
+// ignored files are excluded from the sourcePathes, and re-included files ('!' in .dockerignore) are only
+// the ones excluded by a previous .dockerignore line, so that .dockerignore never adds files to the sourcePathes.
+func DockerignorePatterns(root string, dockerfile string) ([]string, error) {
+	contextDir := path.Dir(filepath.ToSlash(dockerfile))
+	dockerignore := filepath.Join(root, dockerfile+".dockerignore")
+	if !FileExists(dockerignore) {
+		dockerignore = filepath.Join(root, contextDir, ".dockerignore")
+		if !FileExists(dockerignore) {
+			return []string{}, nil
+		}
+	}
+	f, err := os.Open(dockerignore)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s: %v", dockerignore, err)
+	}
+	defer f.Close()
+
+	patterns := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		prefix := dockerignorePrefix
+		if strings.HasPrefix(line, "!") {
+			prefix += "!"
+			line = line[1:]
+		}
+		line = path.Clean(strings.TrimPrefix(line, "/"))
+		if line == "." {
+			continue
+		}
+		// .dockerignore patterns are relative to the build context
+		patterns = append(patterns, prefix+"/"+path.Join(contextDir, line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read %s: %v", dockerignore, err)
+	}
+	return patterns, nil
+}
+
+// WithDockerignore returns the sourcePathes followed by the .dockerignore patterns of a Dockerfile
+func WithDockerignore(root string, sourcePathes []string, dockerfile string) ([]string, error) {
+	patterns, err := DockerignorePatterns(root, dockerfile)
+	if err != nil || len(patterns) == 0 {
+		return sourcePathes, err
+	}
+	result := append([]string{}, sourcePathes...)
+	return append(result, patterns...), nil
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceMatcher(t *testing.T) {
+	require := require.New(t)
+
+	tests := []struct {
+		sourcePathes []string
+		filePath     string
+		expected     bool
+	}{
+		{[]string{"fink_broker"}, "fink_broker/app.py", true},
+		{[]string{"fink_broker"}, "fink_broker_test/app.py", false},
+		{[]string{"Dockerfile"}, "Dockerfile", true},
+		{[]string{"."}, "docs/index.md", true},
+		{[]string{"**/*.py"}, "app.py", true},
+		{[]string{"**/*.py"}, "fink_broker/a/b/app.py", true},
+		{[]string{"*.py"}, "fink_broker/app.py", true},
+		{[]string{"*.py"}, "fink_broker/app.pyc", false},
+		{[]string{"/*.py"}, "fink_broker/app.py", false},
+		{[]string{"/*.py"}, "setup.py", true},
+		{[]string{"fink_broker/*.py"}, "fink_broker/app.py", true},
+		{[]string{"fink_broker/*.py"}, "fink_broker/sub/app.py", false},
+		{[]string{"fink_broker/**"}, "fink_broker/sub/app.py", true},
+		{[]string{"fink_broker", "!**/*.md"}, "fink_broker/README.md", false},
+		{[]string{"fink_broker", "!**/*.md"}, "fink_broker/app.py", true},
+		{[]string{"fink_broker", "!tests/"}, "fink_broker/tests/test_app.py", false},
+		{[]string{"fink_broker", "!tests/"}, "fink_broker/tests", true},
+		{[]string{"fink_broker", "!fink_broker/docs"}, "fink_broker/docs/index.rst", false},
+		// The last matching entry wins
+		{[]string{"fink_broker", "!**/*.md", "fink_broker/CHANGELOG.md"}, "fink_broker/CHANGELOG.md", true},
+		// Exclusions only
+		{[]string{"!docs/"}, "fink_broker/app.py", true},
+		{[]string{"!docs/"}, "docs/index.md", false},
+		{[]string{"fink_broker"}, "", false},
+	}
+	for _, tt := range tests {
+		matcher, err := NewSourceMatcher(tt.sourcePathes)
+		require.NoError(err)
+		require.Equal(tt.expected, matcher.Match(tt.filePath), "sourcePathes=%v filePath=%s", tt.sourcePathes, tt.filePath)
+	}
+
+	matcher, err := NewSourceMatcher([]string{"fink_broker"})
+	require.NoError(err)
+	require.True(matcher.IsPlain())
+	matcher, err = NewSourceMatcher([]string{"fink_broker", "!*.md"})
+	require.NoError(err)
+	require.False(matcher.IsPlain())
+
+	for _, invalid := range []string{"!", "!/", "!../outside", "[z-a]*"} {
+		_, err = NewSourceMatcher([]string{invalid})
+		require.Error(err, invalid)
+	}
+
+	require.NoError(validateSourcePathes([]string{"fink_broker", "**/*.py", "!**/*.md", "!tests/"}))
+	require.NoError(validateSourcePathes([]string{"/setup.py"}))
+	require.Error(validateSourcePathes([]string{"!/"}))
+	require.Error(validateSourcePathes([]string{"fink_broker/../bin"}))
+}
+
+func TestIsFileInSourcePathesPatterns(t *testing.T) {
+	require := require.New(t)
+
+	root := t.TempDir()
+	require.NoError(os.MkdirAll(filepath.Join(root, "fink_broker"), 0755))
+
+	inSourcePathes, err := IsFileInSourcePathes(root, "fink_broker/docs/index.md", []string{"fink_broker", "!**/*.md"})
+	require.NoError(err)
+	require.False(inSourcePathes)
+	inSourcePathes, err = IsFileInSourcePathes(root, "bin/run.py", []string{"fink_broker", "**/*.py"})
+	require.NoError(err)
+	require.True(inSourcePathes)
+
+	// Plain source pathes must exist
+	_, err = IsFileInSourcePathes(root, "bin/run.py", []string{"bin", "**/*.py"})
+	require.Error(err)
+}
+
+func TestDockerignore(t *testing.T) {
+	require := require.New(t)
+
+	root := t.TempDir()
+	require.NoError(os.MkdirAll(filepath.Join(root, "tools"), 0755))
+	require.NoError(os.WriteFile(filepath.Join(root, ".dockerignore"), []byte("# comment\n\n*.md\ndocs\n!docs/api.md\n"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(root, "tools", "Dockerfile.dockerignore"), []byte("/tests\n"), 0644))
+
+	patterns, err := DockerignorePatterns(root, "Dockerfile")
+	require.NoError(err)
+	require.Equal([]string{"dockerignore:/*.md", "dockerignore:/docs", "dockerignore:!/docs/api.md"}, patterns)
+
+	patterns, err = DockerignorePatterns(root, "tools/Dockerfile")
+	require.NoError(err)
+	require.Equal([]string{"dockerignore:/tools/tests"}, patterns)
+
+	patterns, err = DockerignorePatterns(root, "missing/Dockerfile")
+	require.NoError(err)
+	require.Empty(patterns)
+
+	sourcePathes, err := WithDockerignore(root, []string{"fink_broker"}, "Dockerfile")
+	require.NoError(err)
+	require.Equal([]string{"fink_broker", "dockerignore:/*.md", "dockerignore:/docs", "dockerignore:!/docs/api.md"}, sourcePathes)
+	matcher, err := NewSourceMatcher(sourcePathes)
+	require.NoError(err)
+	require.False(matcher.IsPlain())
+	require.True(matcher.Match("fink_broker/app.py"))
+	// Re-included files of the .dockerignore are not added to the sourcePathes
+	require.False(matcher.Match("docs/api.md"))
+
+	sourcePathes, err = WithDockerignore(root, []string{}, "Dockerfile")
+	require.NoError(err)
+	matcher, err = NewSourceMatcher(sourcePathes)
+	require.NoError(err)
+	require.True(matcher.Match("fink_broker/app.py"))
+	require.True(matcher.Match("fink_broker/README.md"))
+	require.False(matcher.Match("README.md"))
+	require.False(matcher.Match("docs/index.md"))
+	require.True(matcher.Match("docs/api.md"))
+
+	// .dockerignore only narrows the sourcePathes
+	require.NoError(os.WriteFile(filepath.Join(root, ".dockerignore"), []byte("*\n!src\n!README.md\n"), 0644))
+	sourcePathes, err = WithDockerignore(root, []string{"src"}, "Dockerfile")
+	require.NoError(err)
+	matcher, err = NewSourceMatcher(sourcePathes)
+	require.NoError(err)
+	require.True(matcher.Match("src/main.go"))
+	require.False(matcher.Match("README.md"))
+	require.False(matcher.Match("setup.py"))
+	sourcePathes, err = WithDockerignore(root, []string{"src", "!src/*.md"}, "Dockerfile")
+	require.NoError(err)
+	matcher, err = NewSourceMatcher(sourcePathes)
+	require.NoError(err)
+	require.False(matcher.Match("src/README.md"))
+}
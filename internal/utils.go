@@ -75,52 +75,39 @@ func IsPathInSubdirectory(absFilePath, absSubdirectory string) (bool, error) {
 	return strings.HasPrefix(absFilePath, absSubdirectory+string(filepath.Separator)), nil
 }
 
-// IsFileInSourcePathes checks if the given file path is in one of the given subdirectories
+// IsFileInSourcePathes checks if the given file path is in one of the given subdirectories or files,
+// or matches the gitignore-style patterns of sourcePathes, see SourceMatcher
 // If subdirectories is empty, it returns true becuase the file path must be in the root directory
 func IsFileInSourcePathes(root string, filePath string, sourcePathes []string) (bool, error) {
 	if len(sourcePathes) == 0 {
 		return true, nil
 	}
 
-	// Get the absolute paths
-	var absFilePath string
 	if filepath.IsAbs(filePath) {
 		return false, fmt.Errorf("invalid argument filePath must be relative: filePath=%q", filePath)
-	} else {
-		absFilePath = filepath.Join(root, filePath)
 	}
 
 	for _, sourcePath := range sourcePathes {
-
-		var absSourcePath string
+		if isSourcePattern(sourcePath) {
+			continue
+		}
 		if filepath.IsAbs(sourcePath) {
 			return false, fmt.Errorf("invalid argument sourcePath must be relative: sourcePath=%q", sourcePath)
-		} else {
-			absSourcePath = filepath.Join(root, sourcePath)
 		}
-
-		isDir, err := isDirectory(absSourcePath)
+		// Plain source pathes must exist
+		_, err := isDirectory(filepath.Join(root, sourcePath))
 		if err != nil {
 			return false, err
 		}
+	}
 
-		if isDir {
-			isInSubdirectory, err := IsPathInSubdirectory(absFilePath, absSourcePath)
-			if err != nil {
-				return false, err
-			}
-
-			if isInSubdirectory {
-				return true, nil
-			}
-		} else {
-			slog.Debug("Check if file path is equal to source path", "filePath", filePath, "sourcePath", sourcePath)
-			if sourcePath == filePath {
-				return true, nil
-			}
-		}
+	matcher, err := NewSourceMatcher(sourcePathes)
+	if err != nil {
+		return false, err
 	}
-	return false, nil
+	match := matcher.Match(filepath.ToSlash(filePath))
+	slog.Debug("Check if file path is in source pathes", "filePath", filePath, "sourcePathes", sourcePathes, "match", match)
+	return match, nil
 }
 
 func isDirectory(path string) (bool, error) {